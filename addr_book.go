@@ -0,0 +1,282 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// addrBookEntry tracks what the local node knows about one address it
+// has heard of but may or may not currently have as a member: when it
+// was last heard from, and how many consecutive ping handshakes with it
+// have failed.
+type addrBookEntry struct {
+	Address  string    `json:"address"`
+	LastSeen time.Time `json:"last_seen"`
+	Failures int       `json:"failures"`
+}
+
+// AddrBook persists addresses the local node has heard about - via PEX
+// or a prior Join - so the cluster can be re-bootstrapped even after
+// every address it was originally configured with has died.
+//
+// Addresses start in the "new" bucket on arrival. Once a direct ping
+// handshake with an address succeeds, AddrBook.MarkTried moves it to the
+// "tried" bucket, which Sample and Join prefer. Addresses are never
+// promoted to memberMap by the book itself - only the ping handshake
+// does that - so a malicious peer handing out bogus addresses via PEX
+// cannot poison membership.
+type AddrBook struct {
+	lock sync.Mutex
+
+	localAddress string
+
+	maxNew   int
+	maxTried int
+
+	newBucket   map[string]*addrBookEntry
+	triedBucket map[string]*addrBookEntry
+}
+
+// NewAddrBook creates an empty AddrBook for localAddress (which is never
+// added to either bucket), capping each bucket at maxNew / maxTried
+// entries.
+func NewAddrBook(localAddress string, maxNew int, maxTried int) *AddrBook {
+	return &AddrBook{
+		localAddress: localAddress,
+		maxNew:       maxNew,
+		maxTried:     maxTried,
+		newBucket:    make(map[string]*addrBookEntry),
+		triedBucket:  make(map[string]*addrBookEntry),
+	}
+}
+
+// AddAddress records that address was heard about (e.g. via PEX), adding
+// it to the new bucket if it is not already known. It is a no-op for the
+// local address or an address already in the tried bucket.
+func (b *AddrBook) AddAddress(address string) {
+	if address == b.localAddress {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.triedBucket[address]; ok {
+		return
+	}
+
+	if entry, ok := b.newBucket[address]; ok {
+		entry.LastSeen = time.Now()
+		return
+	}
+
+	if len(b.newBucket) >= b.maxNew {
+		b.evictLocked(b.newBucket)
+	}
+
+	b.newBucket[address] = &addrBookEntry{Address: address, LastSeen: time.Now()}
+}
+
+// MarkTried records a successful ping handshake with address, moving it
+// into the tried bucket and clearing its failure count.
+func (b *AddrBook) MarkTried(address string) {
+	if address == b.localAddress {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.newBucket, address)
+
+	if len(b.triedBucket) >= b.maxTried {
+		if _, ok := b.triedBucket[address]; !ok {
+			b.evictLocked(b.triedBucket)
+		}
+	}
+
+	b.triedBucket[address] = &addrBookEntry{Address: address, LastSeen: time.Now()}
+}
+
+// MarkFailed records a failed ping handshake with address. An address
+// that has never been confirmed (never reached the tried bucket) is
+// dropped from the new bucket once its failure count passes
+// maxNewFailures, so it stops being offered to Join/Sample.
+func (b *AddrBook) MarkFailed(address string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if entry, ok := b.triedBucket[address]; ok {
+		entry.Failures++
+		return
+	}
+
+	if entry, ok := b.newBucket[address]; ok {
+		entry.Failures++
+		if entry.Failures > maxNewFailures {
+			delete(b.newBucket, address)
+		}
+	}
+}
+
+// maxNewFailures is how many consecutive failed validation pings an
+// unconfirmed address tolerates before being dropped from the new
+// bucket.
+const maxNewFailures = 3
+
+// Sample returns up to k distinct addresses from the book, preferring
+// tried addresses over new ones, for a PEXResponse or a Join fallback.
+func (b *AddrBook) Sample(k int) []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	addrs := make([]string, 0, len(b.triedBucket)+len(b.newBucket))
+	for addr := range b.triedBucket {
+		addrs = append(addrs, addr)
+	}
+	tried := len(addrs)
+	for addr := range b.newBucket {
+		addrs = append(addrs, addr)
+	}
+
+	// Shuffle each bucket on its own so every tried address comes before
+	// any new one.
+	rand.Shuffle(tried, func(i, j int) {
+		addrs[i], addrs[j] = addrs[j], addrs[i]
+	})
+	newAddrs := addrs[tried:]
+	rand.Shuffle(len(newAddrs), func(i, j int) {
+		newAddrs[i], newAddrs[j] = newAddrs[j], newAddrs[i]
+	})
+
+	if k > len(addrs) {
+		k = len(addrs)
+	}
+	return addrs[:k]
+}
+
+// Unvalidated returns up to n addresses from the new bucket that have
+// not yet passed a ping handshake, for periodic validation.
+func (b *AddrBook) Unvalidated(n int) []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	addrs := make([]string, 0, len(b.newBucket))
+	for addr := range b.newBucket {
+		addrs = append(addrs, addr)
+	}
+
+	rand.Shuffle(len(addrs), func(i, j int) {
+		addrs[i], addrs[j] = addrs[j], addrs[i]
+	})
+
+	if n > len(addrs) {
+		n = len(addrs)
+	}
+	return addrs[:n]
+}
+
+// evictLocked drops the oldest, most-failed entry from bucket to make
+// room for a new one. Callers must hold b.lock.
+func (b *AddrBook) evictLocked(bucket map[string]*addrBookEntry) {
+	var worst string
+	for addr, entry := range bucket {
+		if worst == "" {
+			worst = addr
+			continue
+		}
+		current := bucket[worst]
+		if entry.Failures > current.Failures ||
+			(entry.Failures == current.Failures && entry.LastSeen.Before(current.LastSeen)) {
+			worst = addr
+		}
+	}
+
+	if worst != "" {
+		delete(bucket, worst)
+	}
+}
+
+// addrBookFile is the on-disk JSON representation written by Save and
+// read by Load.
+type addrBookFile struct {
+	New   []*addrBookEntry `json:"new"`
+	Tried []*addrBookEntry `json:"tried"`
+}
+
+// Save persists the address book to path as JSON.
+func (b *AddrBook) Save(path string) error {
+	b.lock.Lock()
+	file := addrBookFile{
+		New:   make([]*addrBookEntry, 0, len(b.newBucket)),
+		Tried: make([]*addrBookEntry, 0, len(b.triedBucket)),
+	}
+	for _, entry := range b.newBucket {
+		file.New = append(file.New, entry)
+	}
+	for _, entry := range b.triedBucket {
+		file.Tried = append(file.Tried, entry)
+	}
+	b.lock.Unlock()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// Load replaces the address book's contents with what was previously
+// persisted to path by Save.
+func (b *AddrBook) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file addrBookFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.newBucket = make(map[string]*addrBookEntry, len(file.New))
+	for _, entry := range file.New {
+		if entry.Address == b.localAddress {
+			continue
+		}
+		b.newBucket[entry.Address] = entry
+	}
+
+	b.triedBucket = make(map[string]*addrBookEntry, len(file.Tried))
+	for _, entry := range file.Tried {
+		if entry.Address == b.localAddress {
+			continue
+		}
+		b.triedBucket[entry.Address] = entry
+	}
+
+	return nil
+}
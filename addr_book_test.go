@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAddrBook_AddAddressIgnoresLocalAndDuplicates(t *testing.T) {
+	b := NewAddrBook("local:1", 10, 10)
+
+	b.AddAddress("local:1")
+	b.AddAddress("peer:1")
+	b.AddAddress("peer:1")
+
+	sample := b.Sample(10)
+	if len(sample) != 1 || sample[0] != "peer:1" {
+		t.Fatalf("expected exactly one entry (peer:1), got %+v", sample)
+	}
+}
+
+func TestAddrBook_MarkTriedMovesOutOfNewBucket(t *testing.T) {
+	b := NewAddrBook("local:1", 10, 10)
+	b.AddAddress("peer:1")
+
+	if got := b.Unvalidated(10); len(got) != 1 {
+		t.Fatalf("expected peer:1 to be unvalidated, got %+v", got)
+	}
+
+	b.MarkTried("peer:1")
+
+	if got := b.Unvalidated(10); len(got) != 0 {
+		t.Fatalf("expected peer:1 to no longer be unvalidated after MarkTried, got %+v", got)
+	}
+}
+
+func TestAddrBook_MarkFailedEvictsUnconfirmedAddressAfterThreshold(t *testing.T) {
+	b := NewAddrBook("local:1", 10, 10)
+	b.AddAddress("peer:1")
+
+	for i := 0; i <= maxNewFailures; i++ {
+		b.MarkFailed("peer:1")
+	}
+
+	if got := b.Sample(10); len(got) != 0 {
+		t.Fatalf("expected peer:1 to be evicted after repeated failures, got %+v", got)
+	}
+}
+
+func TestAddrBook_EvictsWhenNewBucketFull(t *testing.T) {
+	b := NewAddrBook("local:1", 2, 10)
+
+	b.AddAddress("peer:1")
+	b.AddAddress("peer:2")
+	b.AddAddress("peer:3")
+
+	if got := b.Sample(10); len(got) > 2 {
+		t.Fatalf("expected the new bucket to stay capped at 2 entries, got %+v", got)
+	}
+}
+
+func TestAddrBook_SaveAndLoadRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "peers.json")
+
+	b := NewAddrBook("local:1", 10, 10)
+	b.AddAddress("peer:1")
+	b.AddAddress("peer:2")
+	b.MarkTried("peer:2")
+
+	if err := b.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded := NewAddrBook("local:1", 10, 10)
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	sample := loaded.Sample(10)
+	if len(sample) != 2 {
+		t.Fatalf("expected 2 addresses after Load, got %+v", sample)
+	}
+
+	if got := loaded.Unvalidated(10); len(got) != 1 || got[0] != "peer:1" {
+		t.Fatalf("expected only peer:1 to still be unvalidated after Load, got %+v", got)
+	}
+}
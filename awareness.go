@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"sync"
+	"time"
+)
+
+// Awareness tracks how healthy the local node currently believes itself
+// to be, as a score bounded in [0, max]. A higher score means the node is
+// less healthy (busier / more likely to miss acks it should otherwise
+// receive in time), and probe/ack timeouts are scaled up accordingly so
+// the node does not wrongly suspect healthy peers while it is under load.
+//
+// This implements the self-awareness mechanism described in the Lifeguard
+// paper (https://arxiv.org/abs/1707.00788).
+type Awareness struct {
+	lock sync.Mutex
+
+	// max is the highest score the local node can reach.
+	max int
+
+	// score is the current health score. 0 is fully healthy.
+	score int
+}
+
+// NewAwareness creates an Awareness bounded to [0, max].
+func NewAwareness(max int) *Awareness {
+	return &Awareness{max: max}
+}
+
+// ApplyDelta adjusts the health score by delta, clamped to [0, max].
+func (a *Awareness) ApplyDelta(delta int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	score := a.score + delta
+	if score < 0 {
+		score = 0
+	}
+	if score > a.max {
+		score = a.max
+	}
+
+	a.score = score
+}
+
+// GetHealthScore returns the current health score.
+func (a *Awareness) GetHealthScore() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.score
+}
+
+// ScaleTimeout scales d by (score+1), so an unhealthy node gives its
+// peers proportionally more time before it starts probing or times out
+// waiting on an ack.
+func (a *Awareness) ScaleTimeout(d time.Duration) time.Duration {
+	return d * time.Duration(a.GetHealthScore()+1)
+}
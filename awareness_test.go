@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAwareness_ApplyDelta_ClampsToBounds(t *testing.T) {
+	a := NewAwareness(3)
+
+	a.ApplyDelta(-5)
+	if score := a.GetHealthScore(); score != 0 {
+		t.Fatalf("expected score clamped to 0, got %d", score)
+	}
+
+	a.ApplyDelta(10)
+	if score := a.GetHealthScore(); score != 3 {
+		t.Fatalf("expected score clamped to max 3, got %d", score)
+	}
+}
+
+func TestAwareness_ScaleTimeout_GrowsUnderLoad(t *testing.T) {
+	a := NewAwareness(8)
+	base := 100 * time.Millisecond
+
+	healthy := a.ScaleTimeout(base)
+	if healthy != base {
+		t.Fatalf("expected scaled timeout to equal base at score 0, got %v", healthy)
+	}
+
+	// Simulate a node under transient load: every missed ack bumps the
+	// score, which should proportionally widen the timeout it gives
+	// peers before it probes or gives up waiting for an ack.
+	for i := 0; i < 3; i++ {
+		a.ApplyDelta(1)
+	}
+
+	loaded := a.ScaleTimeout(base)
+	if loaded <= healthy {
+		t.Fatalf("expected scaled timeout to grow under load: healthy=%v loaded=%v", healthy, loaded)
+	}
+	if loaded != base*4 {
+		t.Fatalf("expected timeout scaled by (score+1)=4, got %v", loaded)
+	}
+
+	// Recovering (successful direct acks) should bring the timeout back
+	// down again.
+	for i := 0; i < 3; i++ {
+		a.ApplyDelta(-1)
+	}
+	if recovered := a.ScaleTimeout(base); recovered != healthy {
+		t.Fatalf("expected timeout to recover to %v, got %v", healthy, recovered)
+	}
+}
+
+func TestAwareness_ConcurrentApplyDelta(t *testing.T) {
+	a := NewAwareness(1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.ApplyDelta(1)
+		}()
+	}
+	wg.Wait()
+
+	if score := a.GetHealthScore(); score != 100 {
+		t.Fatalf("expected score 100 after 100 concurrent increments, got %d", score)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/DE-labtory/swim/pb"
+	"github.com/golang/protobuf/proto"
+)
+
+// PBkStore is pushed to whenever the memberMap changes state, and drawn
+// from to fill the piggyback slot of outgoing ping/ack/indirect-ping
+// messages, so the new state can propagate to the rest of the cluster.
+type PBkStore interface {
+	Push(pbk pb.PiggyBack)
+
+	// Get returns up to n piggyback entries whose combined marshaled
+	// size fits within maxBytes.
+	Get(n int, maxBytes int) []pb.PiggyBack
+}
+
+// broadcastEntry is one pending piggyback update together with how many
+// times it has already been handed out via Get.
+type broadcastEntry struct {
+	pbk       pb.PiggyBack
+	transmits int
+}
+
+// broadcastKey identifies competing updates about the same member, so a
+// higher-incarnation update can invalidate a lower-incarnation one -
+// regardless of either update's status - instead of both riding the
+// queue side by side. Keying on the member alone (not also the status)
+// is what lets a higher-incarnation Alive evict a stale Suspect/Confirm
+// about the same member, and vice versa.
+type broadcastKey struct {
+	memberID string
+}
+
+// BroadcastQueue hands out piggyback data to attach to outgoing
+// ping/ack/indirect-ping messages. Entries are retransmitted at most
+// retransmitMult * ceil(log(N+1)) times, where N is reported by
+// numNodes, so an update dies out once it has almost certainly reached
+// the whole cluster instead of riding forever or not spreading far
+// enough.
+type BroadcastQueue struct {
+	lock sync.Mutex
+
+	retransmitMult int
+	numNodes       func() int
+
+	queue []broadcastEntry
+}
+
+// NewBroadcastQueue creates an empty queue. numNodes is called on every
+// Get to compute the current retransmit limit from the live cluster
+// size.
+func NewBroadcastQueue(retransmitMult int, numNodes func() int) *BroadcastQueue {
+	return &BroadcastQueue{
+		retransmitMult: retransmitMult,
+		numNodes:       numNodes,
+	}
+}
+
+// Push adds a new piggyback entry to the queue, first dropping any
+// existing entry about the same member - whatever its status - whose
+// incarnation is not higher than pbk's - otherwise a flapping node would
+// stack up stale suspect/alive pairs that all keep broadcasting.
+func (b *BroadcastQueue) Push(pbk pb.PiggyBack) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := broadcastKey{memberID: pbk.Id}
+
+	kept := b.queue[:0]
+	for _, entry := range b.queue {
+		if (broadcastKey{memberID: entry.pbk.Id}) == key && entry.pbk.Incarnation <= pbk.Incarnation {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	b.queue = append(kept, broadcastEntry{pbk: pbk})
+}
+
+// Get returns up to n piggyback entries, least-transmitted first, whose
+// combined marshaled size fits within maxBytes. Every returned entry's
+// transmit counter is incremented, and any entry whose counter has now
+// reached the retransmit limit is evicted from the queue.
+func (b *BroadcastQueue) Get(n int, maxBytes int) []pb.PiggyBack {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sort.SliceStable(b.queue, func(i, j int) bool {
+		return b.queue[i].transmits < b.queue[j].transmits
+	})
+
+	limit := b.retransmitLimit()
+
+	result := make([]pb.PiggyBack, 0, n)
+	kept := b.queue[:0]
+	used := 0
+
+	for _, entry := range b.queue {
+		if len(result) < n {
+			if size := marshaledSize(entry.pbk); used+size <= maxBytes {
+				used += size
+				entry.transmits++
+				result = append(result, entry.pbk)
+			}
+		}
+
+		if entry.transmits < limit {
+			kept = append(kept, entry)
+		}
+	}
+
+	b.queue = kept
+	return result
+}
+
+// retransmitLimit computes retransmitMult * ceil(log(N+1)) from the
+// current cluster size.
+func (b *BroadcastQueue) retransmitLimit() int {
+	n := 1
+	if b.numNodes != nil {
+		n = b.numNodes()
+	}
+
+	limit := int(math.Ceil(math.Log(float64(n+1)))) * b.retransmitMult
+	if limit < b.retransmitMult {
+		limit = b.retransmitMult
+	}
+	return limit
+}
+
+// marshaledSize returns the wire size of pbk, used to keep a batch of
+// piggyback entries within a byte budget.
+func marshaledSize(pbk pb.PiggyBack) int {
+	b, err := proto.Marshal(&pbk)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
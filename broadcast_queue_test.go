@@ -0,0 +1,166 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/DE-labtory/swim/pb"
+)
+
+func TestBroadcastQueue_GetIncrementsTransmitsAndEvicts(t *testing.T) {
+	q := NewBroadcastQueue(1, func() int { return 1 })
+
+	q.Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: "peer:1", Address: "peer:1"})
+
+	// retransmitLimit() with N=1 is 1 * ceil(log(2)) == 1 * 1 == 1, so the
+	// entry should be handed out exactly once before being evicted.
+	got := q.Get(10, 1<<20)
+	if len(got) != 1 || got[0].Id != "peer:1" {
+		t.Fatalf("expected to get peer:1 once, got %+v", got)
+	}
+
+	got = q.Get(10, 1<<20)
+	if len(got) != 0 {
+		t.Fatalf("expected the entry to be evicted after reaching its retransmit limit, got %+v", got)
+	}
+}
+
+func TestBroadcastQueue_PushInvalidatesLowerIncarnation(t *testing.T) {
+	q := NewBroadcastQueue(4, func() int { return 3 })
+
+	q.Push(pb.PiggyBack{Type: pb.PiggyBack_Suspect, Id: "peer:1", Address: "peer:1", Incarnation: 1})
+	q.Push(pb.PiggyBack{Type: pb.PiggyBack_Suspect, Id: "peer:1", Address: "peer:1", Incarnation: 2})
+
+	got := q.Get(10, 1<<20)
+	if len(got) != 1 {
+		t.Fatalf("expected the stale incarnation to have been dropped, got %+v", got)
+	}
+	if got[0].Incarnation != 2 {
+		t.Fatalf("expected the surviving entry to be incarnation 2, got %d", got[0].Incarnation)
+	}
+}
+
+func TestBroadcastQueue_GetRespectsByteBudget(t *testing.T) {
+	q := NewBroadcastQueue(4, func() int { return 3 })
+
+	for i := 0; i < 5; i++ {
+		q.Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: fmt.Sprintf("peer:%d", i), Address: fmt.Sprintf("peer:%d", i)})
+	}
+
+	entrySize := marshaledSize(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: "peer:0", Address: "peer:0"})
+
+	got := q.Get(10, entrySize*2)
+	if len(got) > 2 {
+		t.Fatalf("expected at most 2 entries to fit in a %d byte budget, got %d", entrySize*2, len(got))
+	}
+}
+
+func TestBroadcastQueue_GetPrefersLeastTransmitted(t *testing.T) {
+	q := NewBroadcastQueue(100, func() int { return 3 })
+
+	q.Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: "peer:1", Address: "peer:1"})
+	q.Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: "peer:2", Address: "peer:2"})
+
+	// Hand out peer:1 once, so it now has a higher transmit count (1) than
+	// peer:2 (0). Push order ties are broken stably, so peer:1 (pushed
+	// first) is handed out first here.
+	got := q.Get(1, 1<<20)
+	if len(got) != 1 || got[0].Id != "peer:1" {
+		t.Fatalf("expected peer:1 (pushed first, transmits tied at 0) first, got %+v", got)
+	}
+
+	// peer:2 now has fewer transmits (0) than peer:1 (1), so it should
+	// come first in the next Get.
+	got = q.Get(2, 1<<20)
+	if len(got) != 2 || got[0].Id != "peer:2" {
+		t.Fatalf("expected the least-transmitted entry (peer:2) first, got %+v", got)
+	}
+}
+
+// TestBroadcastQueue_PropagatesWithinLogNRounds drives N real
+// BroadcastQueue instances (one per simulated node) through a
+// deterministic recursive-doubling broadcast: in round r, every node
+// that already knows the update draws it from its own queue via Get and
+// forwards it to node (self + 2^r) mod N. This is the standard
+// construction that reaches all N nodes in exactly ceil(log2(N)) rounds,
+// and it exercises the real Push/Get pair on every hop rather than a
+// broadcast-queue-free simulation.
+func TestBroadcastQueue_PropagatesWithinLogNRounds(t *testing.T) {
+	const n = 64
+	const retransmitMult = 3
+	numNodes := func() int { return n }
+
+	queues := make([]*BroadcastQueue, n)
+	for i := range queues {
+		queues[i] = NewBroadcastQueue(retransmitMult, numNodes)
+	}
+
+	known := make([]bool, n)
+	known[0] = true
+	queues[0].Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: "update", Address: "update"})
+
+	maxRounds := int(math.Ceil(math.Log2(float64(n))))
+
+	for round := 0; round < maxRounds; round++ {
+		informed := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			if known[i] {
+				informed = append(informed, i)
+			}
+		}
+
+		for _, i := range informed {
+			got := queues[i].Get(1, 1<<20)
+			if len(got) == 0 {
+				continue
+			}
+			target := (i + (1 << uint(round))) % n
+			known[target] = true
+			queues[target].Push(got[0])
+		}
+	}
+
+	for i, k := range known {
+		if !k {
+			t.Fatalf("node %d never learned the update within %d = ceil(log2(N)) rounds", i, maxRounds)
+		}
+	}
+}
+
+func BenchmarkBroadcastQueue_Get(b *testing.B) {
+	q := NewBroadcastQueue(4, func() int { return 100 })
+	for i := 0; i < 1000; i++ {
+		q.Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: fmt.Sprintf("peer:%d", i), Address: fmt.Sprintf("peer:%d", i)})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Get(maxPiggybackEntries, maxPiggybackBytes)
+	}
+}
+
+func BenchmarkBroadcastQueue_Push(b *testing.B) {
+	q := NewBroadcastQueue(4, func() int { return 100 })
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(pb.PiggyBack{Type: pb.PiggyBack_Alive, Id: fmt.Sprintf("peer:%d", i%1000), Address: fmt.Sprintf("peer:%d", i%1000)})
+	}
+}
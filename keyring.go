@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// Keyring holds the symmetric keys used to encrypt/decrypt SWIM traffic.
+// The key at index 0 is the primary key: it is the only one used to
+// encrypt outgoing packets, while every installed key is tried in turn
+// to decrypt incoming ones. This lets an operator install a new key
+// cluster-wide, wait for it to propagate, and only then flip it to
+// primary with UseKey - rotating keys with no downtime.
+type Keyring struct {
+	lock sync.Mutex
+
+	// keys[0] is always the primary key.
+	keys [][]byte
+}
+
+// NewKeyring creates a Keyring whose primary key is primaryKey, plus any
+// additional keys that should also be accepted for decryption.
+func NewKeyring(primaryKey []byte, additionalKeys ...[]byte) (*Keyring, error) {
+	if err := validateKeySize(primaryKey); err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{keys: [][]byte{primaryKey}}
+
+	for _, key := range additionalKeys {
+		if err := k.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// validateKeySize enforces the AES key sizes: 16, 24 or 32 bytes
+// (AES-128/192/256).
+func validateKeySize(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("keyring: key must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+// AddKey installs key as an additional decryption key, without changing
+// the primary. It is a no-op if the key is already installed.
+func (k *Keyring) AddKey(key []byte) error {
+	if err := validateKeySize(key); err != nil {
+		return err
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if k.indexOf(key) >= 0 {
+		return nil
+	}
+
+	k.keys = append(k.keys, key)
+	return nil
+}
+
+// UseKey promotes an already-installed key to primary, so it is used to
+// encrypt every packet sent from now on.
+func (k *Keyring) UseKey(key []byte) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	idx := k.indexOf(key)
+	if idx < 0 {
+		return fmt.Errorf("keyring: cannot use a key that is not installed")
+	}
+
+	k.keys[0], k.keys[idx] = k.keys[idx], k.keys[0]
+	return nil
+}
+
+// RemoveKey uninstalls key. The current primary key cannot be removed -
+// UseKey another key first.
+func (k *Keyring) RemoveKey(key []byte) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	idx := k.indexOf(key)
+	if idx < 0 {
+		return nil
+	}
+	if idx == 0 {
+		return fmt.Errorf("keyring: cannot remove the primary key, UseKey another key first")
+	}
+
+	k.keys = append(k.keys[:idx], k.keys[idx+1:]...)
+	return nil
+}
+
+// GetKeys returns every installed key, primary first.
+func (k *Keyring) GetKeys() [][]byte {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	keys := make([][]byte, len(k.keys))
+	copy(keys, k.keys)
+	return keys
+}
+
+// PrimaryKey returns the key currently used to encrypt outgoing packets.
+func (k *Keyring) PrimaryKey() []byte {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return k.keys[0]
+}
+
+// indexOf returns the index of key within k.keys, or -1. Callers must
+// hold k.lock.
+func (k *Keyring) indexOf(key []byte) int {
+	for i, existing := range k.keys {
+		if bytes.Equal(existing, key) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"bytes"
+	"testing"
+)
+
+func mustKey(b byte, size int) []byte {
+	key := make([]byte, size)
+	for i := range key {
+		key[i] = b
+	}
+	return key
+}
+
+func TestKeyring_RejectsInvalidKeySize(t *testing.T) {
+	if _, err := NewKeyring(mustKey(1, 10)); err == nil {
+		t.Fatal("expected error for a 10-byte key")
+	}
+}
+
+func TestKeyring_RotationViaUseKey(t *testing.T) {
+	k1 := mustKey(1, 16)
+	k2 := mustKey(2, 16)
+
+	kr, err := NewKeyring(k1)
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+
+	if !bytes.Equal(kr.PrimaryKey(), k1) {
+		t.Fatal("expected k1 to be primary")
+	}
+
+	// Stage the new key cluster-wide before flipping the primary.
+	if err := kr.AddKey(k2); err != nil {
+		t.Fatalf("AddKey failed: %v", err)
+	}
+	if !bytes.Equal(kr.PrimaryKey(), k1) {
+		t.Fatal("adding a key should not change the primary")
+	}
+
+	if err := kr.UseKey(k2); err != nil {
+		t.Fatalf("UseKey failed: %v", err)
+	}
+	if !bytes.Equal(kr.PrimaryKey(), k2) {
+		t.Fatal("expected k2 to become primary after UseKey")
+	}
+
+	keys := kr.GetKeys()
+	if len(keys) != 2 {
+		t.Fatalf("expected both keys to remain installed, got %d", len(keys))
+	}
+}
+
+func TestKeyring_CannotRemovePrimaryKey(t *testing.T) {
+	k1 := mustKey(1, 16)
+	k2 := mustKey(2, 16)
+
+	kr, _ := NewKeyring(k1, k2)
+
+	if err := kr.RemoveKey(k1); err == nil {
+		t.Fatal("expected RemoveKey to refuse removing the primary key")
+	}
+
+	if err := kr.RemoveKey(k2); err != nil {
+		t.Fatalf("expected removing a non-primary key to succeed: %v", err)
+	}
+
+	if len(kr.GetKeys()) != 1 {
+		t.Fatalf("expected 1 key remaining, got %d", len(kr.GetKeys()))
+	}
+}
+
+func TestKeyring_UseKeyRejectsUninstalledKey(t *testing.T) {
+	kr, _ := NewKeyring(mustKey(1, 16))
+
+	if err := kr.UseKey(mustKey(9, 16)); err == nil {
+		t.Fatal("expected UseKey to fail for a key that was never installed")
+	}
+}
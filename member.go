@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+// Status represents the state of a member as seen by the local node.
+type Status int
+
+const (
+	Alive Status = iota
+	Suspect
+	Dead
+)
+
+func (s Status) String() string {
+	switch s {
+	case Alive:
+		return "Alive"
+	case Suspect:
+		return "Suspect"
+	case Dead:
+		return "Dead"
+	default:
+		return "Unknown"
+	}
+}
+
+// Member represents a single node participating in the SWIM cluster as
+// seen from the local node's point of view.
+type Member struct {
+
+	// Address is the dial-able "host:port" of the member and doubles as
+	// its unique id within the memberMap.
+	Address string
+
+	Status Status
+
+	// Incarnation is bumped by the member itself whenever it refutes a
+	// Suspect claim about itself. Higher incarnation always wins when
+	// reconciling competing piggybacks about the same member.
+	Incarnation uint32
+
+	// Coordinate is this member's latest known Vivaldi network
+	// coordinate, used to estimate RTT to it without sending a probe.
+	Coordinate Coordinate
+}
+
+// IsLocal reports whether this member represents the given address.
+func (m Member) IsLocal(address string) bool {
+	return m.Address == address
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// MemberMap keeps the local node's view of every other member of the
+// cluster, keyed by address. It is safe for concurrent use.
+type MemberMap struct {
+	lock sync.RWMutex
+
+	// localAddress is the address of the node that owns this memberMap.
+	localAddress string
+
+	members map[string]Member
+}
+
+// NewMemberMap creates a MemberMap that already contains a single Alive
+// entry for the local node.
+func NewMemberMap(localAddress string) *MemberMap {
+	return &MemberMap{
+		localAddress: localAddress,
+		members: map[string]Member{
+			localAddress: {Address: localAddress, Status: Alive, Incarnation: 0, Coordinate: newCoordinate()},
+		},
+	}
+}
+
+// GetMembers returns a shuffled copy of every known member (local node
+// included) so repeated probe rounds do not always pick in the same order.
+func (m *MemberMap) GetMembers() []Member {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	members := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, member)
+	}
+
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+
+	return members
+}
+
+// Len returns the number of known members, including the local node.
+func (m *MemberMap) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return len(m.members)
+}
+
+// LocalMember returns the member entry that represents this node.
+func (m *MemberMap) LocalMember() Member {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.members[m.localAddress]
+}
+
+// IsLocal reports whether address refers to this node.
+func (m *MemberMap) IsLocal(address string) bool {
+	return address == m.localAddress
+}
+
+// Get returns the member stored for address, if any.
+func (m *MemberMap) Get(address string) (Member, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	member, ok := m.members[address]
+	return member, ok
+}
+
+// Alive marks address as Alive with incarnation, as long as incarnation is
+// not older than what is already known. Returns true if the memberMap
+// changed as a result.
+func (m *MemberMap) Alive(address string, incarnation uint32) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, ok := m.members[address]
+	if ok && existing.Status == Alive && existing.Incarnation >= incarnation {
+		return false
+	}
+
+	coord := newCoordinate()
+	if ok {
+		coord = existing.Coordinate
+	}
+
+	m.members[address] = Member{Address: address, Status: Alive, Incarnation: incarnation, Coordinate: coord}
+	return true
+}
+
+// Suspect marks address as Suspect with incarnation, unless a higher (or
+// equal) incarnation Alive/Suspect record already exists. Returns true if
+// the memberMap changed as a result.
+func (m *MemberMap) Suspect(address string, incarnation uint32) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, ok := m.members[address]
+	if !ok {
+		m.members[address] = Member{Address: address, Status: Suspect, Incarnation: incarnation, Coordinate: newCoordinate()}
+		return true
+	}
+
+	if existing.Incarnation > incarnation {
+		return false
+	}
+
+	if existing.Status == Suspect && existing.Incarnation == incarnation {
+		return false
+	}
+
+	existing.Status = Suspect
+	existing.Incarnation = incarnation
+	m.members[address] = existing
+	return true
+}
+
+// Confirm marks address as Dead. Returns true if the memberMap changed as
+// a result.
+func (m *MemberMap) Confirm(address string, incarnation uint32) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, ok := m.members[address]
+	if ok && existing.Incarnation > incarnation {
+		return false
+	}
+
+	if ok && existing.Status == Dead {
+		return false
+	}
+
+	coord := newCoordinate()
+	if ok {
+		coord = existing.Coordinate
+	}
+
+	m.members[address] = Member{Address: address, Status: Dead, Incarnation: incarnation, Coordinate: coord}
+	return true
+}
+
+// UpdateCoordinate stores the latest known Vivaldi coordinate for
+// address, creating a bare entry for it if it is not already known (e.g.
+// a coordinate piggybacked by a node we have not yet completed a probe
+// with).
+func (m *MemberMap) UpdateCoordinate(address string, coord Coordinate) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, ok := m.members[address]
+	if !ok {
+		existing = Member{Address: address, Status: Alive}
+	}
+
+	existing.Coordinate = coord
+	m.members[address] = existing
+}
+
+// RandomMembers returns up to n distinct Alive members, excluding the
+// local node and any address listed in exclude. Used to pick the k
+// relays for an indirect ping.
+func (m *MemberMap) RandomMembers(n int, exclude ...string) []Member {
+	skip := make(map[string]struct{}, len(exclude)+1)
+	skip[m.localAddress] = struct{}{}
+	for _, addr := range exclude {
+		skip[addr] = struct{}{}
+	}
+
+	candidates := m.GetMembers()
+	picked := make([]Member, 0, n)
+	for _, member := range candidates {
+		if len(picked) == n {
+			break
+		}
+		if member.Status != Alive {
+			continue
+		}
+		if _, ok := skip[member.Address]; ok {
+			continue
+		}
+		picked = append(picked, member)
+	}
+
+	return picked
+}
+
+// Reset is called once every probe round. Membership itself is not
+// cleared here - Dead members are only ever removed explicitly - it only
+// exists as the hook the failure detector calls between rounds.
+func (m *MemberMap) Reset() {
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import "testing"
+
+func TestMemberMap_SuspectThenHigherIncarnationAliveRefutes(t *testing.T) {
+	m := NewMemberMap("local:1")
+	m.Alive("peer:1", 0)
+
+	if !m.Suspect("peer:1", 0) {
+		t.Fatal("expected Suspect with matching incarnation to change state")
+	}
+
+	if !m.Alive("peer:1", 1) {
+		t.Fatal("expected higher-incarnation Alive to refute the Suspect")
+	}
+
+	member, ok := m.Get("peer:1")
+	if !ok || member.Status != Alive {
+		t.Fatalf("expected peer:1 to be Alive, got %+v", member)
+	}
+}
+
+func TestMemberMap_StaleSuspectIsIgnored(t *testing.T) {
+	m := NewMemberMap("local:1")
+	m.Alive("peer:1", 5)
+
+	if m.Suspect("peer:1", 2) {
+		t.Fatal("expected Suspect with a lower incarnation to be ignored")
+	}
+
+	member, _ := m.Get("peer:1")
+	if member.Status != Alive {
+		t.Fatalf("expected peer:1 to remain Alive, got %v", member.Status)
+	}
+}
+
+func TestMemberMap_ConfirmMarksDead(t *testing.T) {
+	m := NewMemberMap("local:1")
+	m.Alive("peer:1", 0)
+
+	if !m.Confirm("peer:1", 0) {
+		t.Fatal("expected Confirm to change state to Dead")
+	}
+
+	member, _ := m.Get("peer:1")
+	if member.Status != Dead {
+		t.Fatalf("expected peer:1 to be Dead, got %v", member.Status)
+	}
+}
+
+func TestMemberMap_RandomMembersExcludesLocalAndTarget(t *testing.T) {
+	m := NewMemberMap("local:1")
+	m.Alive("peer:1", 0)
+	m.Alive("peer:2", 0)
+	m.Alive("peer:3", 0)
+
+	relays := m.RandomMembers(2, "peer:1")
+	if len(relays) != 2 {
+		t.Fatalf("expected 2 relays, got %d", len(relays))
+	}
+
+	for _, relay := range relays {
+		if relay.Address == "local:1" || relay.Address == "peer:1" {
+			t.Fatalf("relay %s should have been excluded", relay.Address)
+		}
+	}
+}
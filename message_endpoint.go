@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"github.com/DE-labtory/swim/pb"
+	"github.com/golang/protobuf/proto"
+	"github.com/it-chain/iLogger"
+)
+
+// MessageEndpointConfig configures the MessageEndpoint.
+type MessageEndpointConfig struct {
+
+	// Keyring, if set, encrypts every outgoing packet with its primary
+	// key and tries each of its keys in turn to decrypt inbound ones.
+	// nil disables encryption entirely.
+	Keyring *Keyring
+
+	// RequireEncryption rejects any inbound packet that is not
+	// encrypted, instead of treating it as plaintext. Encrypted packets
+	// that no installed key authenticates are dropped either way. Leave
+	// false while migrating a cluster
+	// to encryption key-by-key; flip to true once every member has the
+	// new key installed.
+	RequireEncryption bool
+}
+
+// MessageEndpoint is the single point messages flow through on their way
+// to and from the wire: it serializes/deserializes pb.Message and hands
+// inbound messages to the configured MessageHandler.
+type MessageEndpoint struct {
+	config MessageEndpointConfig
+
+	transport *PacketTransport
+
+	handler MessageHandler
+
+	awareness *Awareness
+
+	quitCh chan struct{}
+}
+
+// NewMessageEndpoint wires transport to handler and starts the receive
+// loop.
+func NewMessageEndpoint(config MessageEndpointConfig, transport *PacketTransport, handler MessageHandler, awareness *Awareness) (*MessageEndpoint, error) {
+	endpoint := &MessageEndpoint{
+		config:    config,
+		transport: transport,
+		handler:   handler,
+		awareness: awareness,
+		quitCh:    make(chan struct{}),
+	}
+
+	go endpoint.listen()
+
+	return endpoint, nil
+}
+
+func (e *MessageEndpoint) listen() {
+	for {
+		select {
+		case packet := <-e.transport.PacketCh():
+			e.handlePacket(packet)
+		case <-e.quitCh:
+			return
+		}
+	}
+}
+
+func (e *MessageEndpoint) handlePacket(packet Packet) {
+	msg := pb.Message{}
+	if err := proto.Unmarshal(packet.Buf, &msg); err != nil {
+		iLogger.Error(nil, err.Error())
+		return
+	}
+
+	e.handler.handle(msg)
+}
+
+// Send serializes msg and writes it to address over the PacketTransport.
+func (e *MessageEndpoint) Send(address string, msg pb.Message) error {
+	b, err := proto.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.transport.WriteTo(b, address)
+	return err
+}
+
+// Shutdown stops the receive loop and closes the underlying transport.
+func (e *MessageEndpoint) Shutdown() {
+	close(e.quitCh)
+	e.transport.Shutdown()
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPacketEncryption_RoundTrip(t *testing.T) {
+	kr, _ := NewKeyring(mustKey(7, 32))
+	cache := newNonceCache(time.Minute, 128)
+
+	plaintext := []byte("ping message payload")
+
+	encrypted, err := encryptPacket(kr, plaintext)
+	if err != nil {
+		t.Fatalf("encryptPacket failed: %v", err)
+	}
+
+	got, err := decryptPacket(kr, cache, encrypted)
+	if err != nil {
+		t.Fatalf("decryptPacket failed: %v", err)
+	}
+
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("round-tripped plaintext mismatch: got %q want %q", got, plaintext)
+	}
+}
+
+func TestPacketEncryption_RotationStillDecrypts(t *testing.T) {
+	oldKey := mustKey(1, 16)
+	newKey := mustKey(2, 16)
+
+	sender, _ := NewKeyring(oldKey)
+	receiver, _ := NewKeyring(newKey, oldKey)
+	cache := newNonceCache(time.Minute, 128)
+
+	encrypted, err := encryptPacket(sender, []byte("hello"))
+	if err != nil {
+		t.Fatalf("encryptPacket failed: %v", err)
+	}
+
+	if _, err := decryptPacket(receiver, cache, encrypted); err != nil {
+		t.Fatalf("expected receiver to decrypt with its secondary key: %v", err)
+	}
+}
+
+func TestPacketEncryption_MismatchedKeysFail(t *testing.T) {
+	sender, _ := NewKeyring(mustKey(1, 16))
+	receiver, _ := NewKeyring(mustKey(2, 16))
+	cache := newNonceCache(time.Minute, 128)
+
+	encrypted, _ := encryptPacket(sender, []byte("hello"))
+
+	if _, err := decryptPacket(receiver, cache, encrypted); err == nil {
+		t.Fatal("expected decryption to fail when peers have no key in common")
+	}
+}
+
+func TestPacketEncryption_RejectsReplayedNonce(t *testing.T) {
+	kr, _ := NewKeyring(mustKey(3, 16))
+	cache := newNonceCache(time.Minute, 128)
+
+	encrypted, _ := encryptPacket(kr, []byte("hello"))
+
+	if _, err := decryptPacket(kr, cache, encrypted); err != nil {
+		t.Fatalf("first decrypt should succeed: %v", err)
+	}
+
+	if _, err := decryptPacket(kr, cache, encrypted); err == nil {
+		t.Fatal("expected replayed packet to be rejected")
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/it-chain/iLogger"
+)
+
+// Packet is a single inbound datagram together with the address it
+// arrived from.
+type Packet struct {
+	Buf  []byte
+	From net.Addr
+}
+
+type PacketTransportConfig struct {
+	BindAddress string
+	BindPort    int
+
+	// Keyring, if set, encrypts every outgoing packet with its primary
+	// key and is tried key-by-key to decrypt inbound ones.
+	Keyring *Keyring
+
+	// RequireEncryption drops any inbound packet that is not encrypted,
+	// instead of falling back to treating it as plaintext.
+	RequireEncryption bool
+}
+
+// PacketTransport is the UDP transport used for ping/ack/indirect-ping
+// traffic - the unreliable, best-effort half of SWIM's communication.
+type PacketTransport struct {
+	config *PacketTransportConfig
+
+	conn *net.UDPConn
+
+	keyring    *Keyring
+	nonceCache *nonceCache
+
+	// decryptFailures counts inbound packets dropped for failing to
+	// decrypt under any installed key, or for being unencrypted while
+	// RequireEncryption is set.
+	decryptFailures uint64
+
+	packetCh chan Packet
+	quitCh   chan struct{}
+}
+
+// NewPacketTransport binds a UDP socket on config.BindAddress:BindPort
+// and starts listening for inbound packets.
+func NewPacketTransport(config *PacketTransportConfig) (*PacketTransport, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", config.BindAddress, config.BindPort))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &PacketTransport{
+		config:     config,
+		conn:       conn,
+		keyring:    config.Keyring,
+		nonceCache: newNonceCache(5*time.Minute, 8192),
+		packetCh:   make(chan Packet),
+		quitCh:     make(chan struct{}),
+	}
+
+	go transport.listen()
+
+	return transport, nil
+}
+
+func (t *PacketTransport) listen() {
+	buf := make([]byte, 65536)
+
+	for {
+		n, addr, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-t.quitCh:
+				return
+			default:
+				continue
+			}
+		}
+
+		packetBuf, ok := t.decode(buf[:n])
+		if !ok {
+			continue
+		}
+
+		select {
+		case t.packetCh <- Packet{Buf: packetBuf, From: addr}:
+		case <-t.quitCh:
+			return
+		}
+	}
+}
+
+// decode turns a raw datagram into the plaintext pb.Message bytes,
+// decrypting it first if it is encrypted (or rejecting it outright if
+// RequireEncryption is set and it is not).
+func (t *PacketTransport) decode(raw []byte) ([]byte, bool) {
+	if !isEncryptedPacket(raw) {
+		if t.config.RequireEncryption {
+			atomic.AddUint64(&t.decryptFailures, 1)
+			iLogger.Error(nil, "packet_transport: dropping unencrypted packet, encryption is required")
+			return nil, false
+		}
+		return append([]byte(nil), raw...), true
+	}
+
+	if t.keyring == nil {
+		atomic.AddUint64(&t.decryptFailures, 1)
+		iLogger.Error(nil, "packet_transport: dropping encrypted packet, no keyring configured")
+		return nil, false
+	}
+
+	plaintext, err := decryptPacket(t.keyring, t.nonceCache, raw)
+	if err != nil {
+		atomic.AddUint64(&t.decryptFailures, 1)
+		iLogger.Error(nil, err.Error())
+		return nil, false
+	}
+
+	return plaintext, true
+}
+
+// DecryptFailures returns the number of inbound packets dropped so far
+// for failing to authenticate, being unencrypted while required, or
+// being replayed.
+func (t *PacketTransport) DecryptFailures() uint64 {
+	return atomic.LoadUint64(&t.decryptFailures)
+}
+
+// PacketCh returns the channel inbound packets are delivered on.
+func (t *PacketTransport) PacketCh() <-chan Packet {
+	return t.packetCh
+}
+
+// WriteTo sends b to address over UDP, encrypting it first if a Keyring
+// is configured.
+func (t *PacketTransport) WriteTo(b []byte, address string) (int, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return 0, err
+	}
+
+	out := b
+	if t.keyring != nil {
+		encrypted, err := encryptPacket(t.keyring, b)
+		if err != nil {
+			return 0, err
+		}
+		out = encrypted
+	}
+
+	return t.conn.WriteTo(out, addr)
+}
+
+// Shutdown stops the listen loop and closes the socket.
+func (t *PacketTransport) Shutdown() error {
+	close(t.quitCh)
+	return t.conn.Close()
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"time"
+
+	"github.com/DE-labtory/swim/pb"
+)
+
+// maxPEXValidationsPerRound bounds how many not-yet-confirmed addresses
+// are ping-validated per PEX tick, so a book full of bogus PEX entries
+// cannot turn into unbounded outbound probing.
+const maxPEXValidationsPerRound = 1
+
+// periodicPEX runs independently of the probe loop: every PEXInterval it
+// asks a random member for a sample of its address book, and attempts to
+// validate a sample of this node's own not-yet-confirmed addresses by
+// pinging them directly. Disabled when PEXInterval <= 0.
+func (s *SWIM) periodicPEX() {
+	if s.config.PEXInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.PEXInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if target := s.memberMap.RandomMembers(1); len(target) > 0 {
+				s.sendPEXRequest(target[0].Address)
+			}
+
+			for _, addr := range s.addrBook.Unvalidated(maxPEXValidationsPerRound) {
+				s.validateCandidate(addr)
+			}
+		case <-s.quitFD:
+			return
+		}
+	}
+}
+
+func (s *SWIM) sendPEXRequest(target string) {
+	s.messageEndpoint.Send(target, pb.Message{
+		Id:      s.nextMessageID(),
+		Address: s.address,
+		Payload: &pb.Message_PEXRequest{
+			PEXRequest: &pb.PEXRequest{K: uint32(s.config.PEXSampleSize)},
+		},
+	})
+}
+
+// pexRequestHandler answers with a sample of this node's address book.
+func (s *SWIM) pexRequestHandler(msg pb.Message) {
+	req := msg.GetPEXRequest()
+	if req == nil {
+		return
+	}
+
+	s.messageEndpoint.Send(msg.Address, pb.Message{
+		Id:      msg.Id,
+		Address: s.address,
+		Payload: &pb.Message_PEXResponse{
+			PEXResponse: &pb.PEXResponse{Addresses: s.addrBook.Sample(int(req.K))},
+		},
+	})
+}
+
+// pexResponseHandler feeds addresses received via PEX into the address
+// book. They are deliberately NOT added to memberMap here - only
+// validateCandidate, after a successful direct ping handshake, does
+// that - so a peer cannot poison membership by handing out addresses it
+// controls.
+func (s *SWIM) pexResponseHandler(msg pb.Message) {
+	resp := msg.GetPEXResponse()
+	if resp == nil {
+		return
+	}
+
+	for _, addr := range resp.Addresses {
+		if s.memberMap.IsLocal(addr) {
+			continue
+		}
+		if _, ok := s.memberMap.Get(addr); ok {
+			continue
+		}
+		s.addrBook.AddAddress(addr)
+	}
+}
+
+// validateCandidate sends a single direct ping to address - an address
+// this node only knows about via the address book, not memberMap - and
+// promotes it to a full member on success.
+func (s *SWIM) validateCandidate(address string) {
+	msgID := s.nextMessageID()
+	ackCh := s.registerAckWaiter(msgID)
+	defer s.deregisterAckWaiter(msgID)
+
+	s.sendPing(msgID, address)
+
+	ackTimeOut := s.awareness.ScaleTimeout(time.Duration(s.config.AckTimeOut) * time.Millisecond)
+	timer := time.NewTimer(ackTimeOut)
+	defer timer.Stop()
+
+	select {
+	case <-ackCh:
+		s.addrBook.MarkTried(address)
+		s.memberMap.Alive(address, 0)
+	case <-timer.C:
+		s.addrBook.MarkFailed(address)
+	}
+}
+
+// SavePeers persists the address book to path, so it can bootstrap the
+// cluster again on restart via LoadPeers even if every address in the
+// current memberMap is unreachable by then.
+func (s *SWIM) SavePeers(path string) error {
+	return s.addrBook.Save(path)
+}
+
+// LoadPeers replaces the address book's contents with what was
+// previously persisted by SavePeers. It does not touch memberMap -
+// loaded addresses still have to answer a validation ping (via
+// periodicPEX) or a push/pull exchange (via Join) before becoming
+// members.
+func (s *SWIM) LoadPeers(path string) error {
+	return s.addrBook.Load(path)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPEX_RequestResponseExchangesAddressBook(t *testing.T) {
+	nodeA := newTestSWIM(t, 18500)
+	nodeA.Start()
+	defer nodeA.ShutDown()
+
+	nodeB := newTestSWIM(t, 18501)
+	nodeB.Start()
+	defer nodeB.ShutDown()
+
+	nodeA.addrBook.AddAddress("127.0.0.1:18599")
+
+	nodeB.sendPEXRequest(nodeA.address)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if got := nodeB.addrBook.Sample(10); len(got) > 0 {
+			if got[0] == "127.0.0.1:18599" {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected nodeB's address book to learn 127.0.0.1:18599 from nodeA via PEX")
+}
+
+func TestPEX_ResponseDoesNotAutoAddToMemberMap(t *testing.T) {
+	nodeA := newTestSWIM(t, 18510)
+	nodeA.Start()
+	defer nodeA.ShutDown()
+
+	nodeB := newTestSWIM(t, 18511)
+	nodeB.Start()
+	defer nodeB.ShutDown()
+
+	nodeA.addrBook.AddAddress("127.0.0.1:18599")
+	nodeB.sendPEXRequest(nodeA.address)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(nodeB.addrBook.Sample(10)) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, ok := nodeB.memberMap.Get("127.0.0.1:18599"); ok {
+		t.Fatal("expected a PEX-learned address to not be added to memberMap without a successful ping handshake")
+	}
+}
+
+// TestJoin_FallsBackToAddrBookAfterSeedsDie simulates a node rejoining
+// the cluster purely from its persisted address book, after every seed
+// address it was originally configured with has died.
+func TestJoin_FallsBackToAddrBookAfterSeedsDie(t *testing.T) {
+	seed := newTestSWIM(t, 18520)
+	seed.Start()
+
+	survivor := newTestSWIM(t, 18521)
+	survivor.Start()
+	defer survivor.ShutDown()
+
+	if err := survivor.Join([]string{seed.address}); err != nil {
+		t.Fatalf("survivor failed to join seed: %v", err)
+	}
+	waitForConvergence(t, []*SWIM{seed, survivor}, time.Second)
+
+	// Simulate survivor having already learned about a third node via
+	// PEX, independent of this test's timing.
+	survivor.addrBook.AddAddress(survivor.address) // no-op: never added for self
+	thirdPartyAddr := "127.0.0.1:18522"
+	survivor.addrBook.AddAddress(thirdPartyAddr)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "peers.json")
+	if err := survivor.SavePeers(path); err != nil {
+		t.Fatalf("SavePeers failed: %v", err)
+	}
+
+	// The node referenced by thirdPartyAddr above.
+	third := newTestSWIM(t, 18522)
+	third.Start()
+	defer third.ShutDown()
+	if err := third.Join([]string{seed.address}); err != nil {
+		t.Fatalf("third failed to join seed: %v", err)
+	}
+	waitForConvergence(t, []*SWIM{seed, survivor, third}, time.Second)
+
+	// Now every original seed dies.
+	seed.ShutDown()
+
+	restarted := newTestSWIM(t, 18523)
+	restarted.Start()
+	defer restarted.ShutDown()
+
+	if err := restarted.LoadPeers(path); err != nil {
+		t.Fatalf("LoadPeers failed: %v", err)
+	}
+
+	if err := restarted.Join(nil); err != nil {
+		t.Fatalf("expected Join to fall back to the persisted address book and succeed via the still-alive third node, got: %v", err)
+	}
+
+	// The cluster now consists of 4 addresses total (seed, survivor,
+	// third, restarted) - seed is never pruned from memberMap just
+	// because it stopped responding, it would still need to be confirmed
+	// Dead via suspicion - so convergence here means Len() reaching 4,
+	// not 2.
+	const wantMembers = 4
+	waitForMemberCount(t, []*SWIM{restarted, third}, wantMembers, time.Second)
+}
+
+// waitForMemberCount polls every 10ms, up to timeout, for every node in
+// nodes to report exactly want members in its memberMap.
+func waitForMemberCount(t *testing.T, nodes []*SWIM, want int, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		converged := true
+		for _, node := range nodes {
+			if node.memberMap.Len() != want {
+				converged = false
+				break
+			}
+		}
+		if converged {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, node := range nodes {
+		t.Logf("node %s knows %d members", node.address, node.memberMap.Len())
+	}
+	t.Fatalf("memberMaps did not converge to %d members within %v", want, timeout)
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/DE-labtory/swim/pb"
+	"github.com/golang/protobuf/proto"
+	"github.com/it-chain/iLogger"
+)
+
+// pushPullProtocolVersion is written as the first byte of every
+// StreamTransport connection so the two ends can refuse to talk to an
+// incompatible peer instead of misparsing its frames.
+const pushPullProtocolVersion byte = 1
+
+// maxPushPullSize bounds how large a single PushPull payload may be, so a
+// misbehaving or compromised peer cannot force unbounded memory growth.
+const maxPushPullSize = 4 << 20 // 4MB
+
+// defaultPushPullTimeout is used when Config.PushPullTimeout is <= 0. A
+// full state sync needs a TCP handshake plus a two-way exchange, so it
+// gets a more generous budget than a single UDP ack.
+const defaultPushPullTimeout = time.Second
+
+// writePushPull frames pp as [version-byte | 4-byte big-endian length |
+// protobuf payload] and writes it to conn.
+func writePushPull(conn net.Conn, pp *pb.PushPull) error {
+	payload, err := proto.Marshal(pp)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxPushPullSize {
+		return fmt.Errorf("pushpull: payload of %d bytes exceeds max %d", len(payload), maxPushPullSize)
+	}
+
+	header := make([]byte, 5)
+	header[0] = pushPullProtocolVersion
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+
+	if _, err := conn.Write(header); err != nil {
+		return err
+	}
+
+	_, err = conn.Write(payload)
+	return err
+}
+
+// readPushPull reads a frame written by writePushPull off conn.
+func readPushPull(conn net.Conn) (*pb.PushPull, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+
+	if header[0] != pushPullProtocolVersion {
+		return nil, fmt.Errorf("pushpull: unsupported protocol version %d", header[0])
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxPushPullSize {
+		return nil, fmt.Errorf("pushpull: payload of %d bytes exceeds max %d", size, maxPushPullSize)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	pp := &pb.PushPull{}
+	if err := proto.Unmarshal(buf, pp); err != nil {
+		return nil, err
+	}
+
+	return pp, nil
+}
+
+// Join dials each of peerAddresses in turn until one succeeds, exchanges
+// a full push/pull of membership state with it, and returns. A cold node
+// cannot converge from UDP piggybacks alone - it needs at least one full
+// view of the cluster to start from.
+//
+// If peerAddresses is empty, Join falls back to sampling the address
+// book (see addr_book.go) - addresses remembered from a previous run or
+// learned via PEX - so a node can still re-bootstrap once every
+// originally configured seed has died.
+func (s *SWIM) Join(peerAddresses []string) error {
+	if len(peerAddresses) == 0 {
+		peerAddresses = s.addrBook.Sample(s.config.PEXSampleSize)
+	}
+
+	if len(peerAddresses) == 0 {
+		return fmt.Errorf("swim: Join requires at least one peer address, and the address book is empty")
+	}
+
+	var lastErr error
+	for _, addr := range peerAddresses {
+		s.addrBook.AddAddress(addr)
+
+		if err := s.pushPullWith(addr); err != nil {
+			lastErr = err
+			continue
+		}
+
+		s.addrBook.MarkTried(addr)
+		return nil
+	}
+
+	return fmt.Errorf("swim: failed to join via any of %d peer(s): %w", len(peerAddresses), lastErr)
+}
+
+// pushPullWith dials addr, pushes the local membership view, pulls back
+// the remote's, and merges it in using the same Alive/Suspect/Confirm
+// rules as piggyback gossip.
+func (s *SWIM) pushPullWith(addr string) error {
+	pushPullTimeout := time.Duration(s.config.PushPullTimeout) * time.Millisecond
+	if pushPullTimeout <= 0 {
+		pushPullTimeout = defaultPushPullTimeout
+	}
+
+	dialTimeout := s.awareness.ScaleTimeout(pushPullTimeout)
+	deadline := time.Now().Add(dialTimeout)
+
+	conn, err := s.streamTransport.DialTimeout(addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The same budget bounds the dial and the exchange that follows.
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	local := &pb.PushPull{
+		Version:    uint32(pushPullProtocolVersion),
+		Members:    s.localFullState(),
+		PiggyBacks: s.nextPiggyBacks(),
+	}
+
+	if err := writePushPull(conn, local); err != nil {
+		return err
+	}
+
+	remote, err := readPushPull(conn)
+	if err != nil {
+		return err
+	}
+
+	s.mergeFullState(addr, remote.Members, remote.PiggyBacks)
+	return nil
+}
+
+// listenPushPull accepts inbound StreamTransport connections and answers
+// each with a push/pull exchange, symmetric to pushPullWith.
+func (s *SWIM) listenPushPull() {
+	for {
+		select {
+		case conn := <-s.streamTransport.ConnCh():
+			go s.handleInboundPushPull(conn)
+		case <-s.quitFD:
+			return
+		}
+	}
+}
+
+func (s *SWIM) handleInboundPushPull(conn net.Conn) {
+	defer conn.Close()
+
+	remote, err := readPushPull(conn)
+	if err != nil {
+		iLogger.Error(nil, err.Error())
+		return
+	}
+
+	local := &pb.PushPull{
+		Version:    uint32(pushPullProtocolVersion),
+		Members:    s.localFullState(),
+		PiggyBacks: s.nextPiggyBacks(),
+	}
+
+	if err := writePushPull(conn, local); err != nil {
+		iLogger.Error(nil, err.Error())
+		return
+	}
+
+	s.mergeFullState(conn.RemoteAddr().String(), remote.Members, remote.PiggyBacks)
+}
+
+// periodicPushPull runs a push/pull with a random member every
+// PushPullInterval, to cheaply repair state that gossip missed, e.g.
+// after a partition heals. Disabled when PushPullInterval <= 0.
+func (s *SWIM) periodicPushPull() {
+	if s.config.PushPullInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.PushPullInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			target := s.memberMap.RandomMembers(1)
+			if len(target) == 0 {
+				continue
+			}
+			if err := s.pushPullWith(target[0].Address); err != nil {
+				iLogger.Error(nil, err.Error())
+			}
+		case <-s.quitFD:
+			return
+		}
+	}
+}
+
+// localFullState snapshots the memberMap into the wire format exchanged
+// during push/pull.
+func (s *SWIM) localFullState() []*pb.FullStateEntry {
+	members := s.memberMap.GetMembers()
+
+	entries := make([]*pb.FullStateEntry, 0, len(members))
+	for _, member := range members {
+		entries = append(entries, &pb.FullStateEntry{
+			Address:     member.Address,
+			Incarnation: member.Incarnation,
+			Status:      statusToPiggyBackType(member.Status),
+		})
+	}
+
+	return entries
+}
+
+// mergeFullState folds a remote's membership view into the local
+// memberMap by replaying each entry - and any piggybacks the remote had
+// pending, so a node freshly joining doesn't have to wait for a piggyback
+// to reach it separately - through handlePbk, the exact same
+// Alive/Suspect/Confirm reconciliation and suspicion-timer wiring used
+// for piggyback gossip.
+func (s *SWIM) mergeFullState(from string, entries []*pb.FullStateEntry, piggyBacks []*pb.PiggyBack) {
+	for _, entry := range entries {
+		s.handlePbk(from, &pb.PiggyBack{
+			Type:        entry.Status,
+			Id:          entry.Address,
+			Address:     entry.Address,
+			Incarnation: entry.Incarnation,
+		})
+	}
+
+	for _, piggyBack := range piggyBacks {
+		s.handlePbk(from, piggyBack)
+	}
+}
+
+func statusToPiggyBackType(status Status) pb.PiggyBack_Type {
+	switch status {
+	case Suspect:
+		return pb.PiggyBack_Suspect
+	case Dead:
+		return pb.PiggyBack_Confirm
+	default:
+		return pb.PiggyBack_Alive
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"net"
+	"testing"
+
+	"github.com/DE-labtory/swim/pb"
+)
+
+func TestPushPullFrame_RoundTrip(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	sent := &pb.PushPull{
+		Version: uint32(pushPullProtocolVersion),
+		Members: []*pb.FullStateEntry{
+			{Address: "127.0.0.1:9001", Incarnation: 3, Status: pb.PiggyBack_Alive},
+		},
+	}
+
+	go writePushPull(client, sent)
+
+	got, err := readPushPull(server)
+	if err != nil {
+		t.Fatalf("readPushPull failed: %v", err)
+	}
+
+	if len(got.Members) != 1 || got.Members[0].Address != "127.0.0.1:9001" || got.Members[0].Incarnation != 3 {
+		t.Fatalf("unexpected round-tripped PushPull: %+v", got)
+	}
+}
+
+func TestPushPullFrame_RejectsUnknownProtocolVersion(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		client.Write([]byte{0xFF, 0, 0, 0, 0})
+	}()
+
+	if _, err := readPushPull(server); err == nil {
+		t.Fatal("expected readPushPull to reject an unknown protocol version")
+	}
+}
+
+func TestPushPullFrame_RejectsOversizedPayload(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		header := []byte{pushPullProtocolVersion, 0xFF, 0xFF, 0xFF, 0xFF}
+		client.Write(header)
+	}()
+
+	if _, err := readPushPull(server); err == nil {
+		t.Fatal("expected readPushPull to reject an oversized payload")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestSWIM(t *testing.T, port int) *SWIM {
+	t.Helper()
+
+	config := &Config{
+		RetransmitMult:   3,
+		T:                20,
+		AckTimeOut:       10,
+		K:                3,
+		SuspicionMult:    4,
+		PushPullInterval: 20,
+		PushPullTimeout:  2000,
+		PEXInterval:      20,
+		PEXSampleSize:    8,
+		BindAddress:      "127.0.0.1",
+		BindPort:         port,
+	}
+
+	return New(config, MessageEndpointConfig{}, NewAwareness(8))
+}
+
+// waitForConvergence polls every 10ms, up to timeout, for every node in
+// nodes to know about every other node.
+func waitForConvergence(t *testing.T, nodes []*SWIM, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		converged := true
+		for _, node := range nodes {
+			if node.memberMap.Len() != len(nodes) {
+				converged = false
+				break
+			}
+		}
+		if converged {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, node := range nodes {
+		t.Logf("node %s knows %d members", node.address, node.memberMap.Len())
+	}
+	t.Fatalf("memberMaps did not converge to %d members within %v", len(nodes), timeout)
+}
+
+func TestJoin_ConvergesAcrossInProcessCluster(t *testing.T) {
+	const n = 6
+	basePort := 18100
+
+	nodes := make([]*SWIM, n)
+	for i := 0; i < n; i++ {
+		nodes[i] = newTestSWIM(t, basePort+i)
+		nodes[i].Start()
+	}
+	defer func() {
+		for _, node := range nodes {
+			node.ShutDown()
+		}
+	}()
+
+	seed := nodes[0].address
+	for i := 1; i < n; i++ {
+		if err := nodes[i].Join([]string{seed}); err != nil {
+			t.Fatalf("node %d failed to join: %v", i, err)
+		}
+	}
+
+	waitForConvergence(t, nodes, 2*time.Second)
+}
+
+// TestJoin_ConvergesAfterPartitionHeals simulates two halves of a
+// cluster that bootstrapped independently (as if split by a partition)
+// and verifies that a single bridging Join, followed by periodic
+// push/pull, is enough for every node to learn about every other node.
+func TestJoin_ConvergesAfterPartitionHeals(t *testing.T) {
+	const halfSize = 3
+	basePort := 18200
+
+	groupA := make([]*SWIM, halfSize)
+	groupB := make([]*SWIM, halfSize)
+
+	for i := 0; i < halfSize; i++ {
+		groupA[i] = newTestSWIM(t, basePort+i)
+		groupA[i].Start()
+
+		groupB[i] = newTestSWIM(t, basePort+halfSize+i)
+		groupB[i].Start()
+	}
+
+	all := append(append([]*SWIM{}, groupA...), groupB...)
+	defer func() {
+		for _, node := range all {
+			node.ShutDown()
+		}
+	}()
+
+	// Each half converges internally first, as if the two halves were
+	// partitioned from one another.
+	for i := 1; i < halfSize; i++ {
+		if err := groupA[i].Join([]string{groupA[0].address}); err != nil {
+			t.Fatalf("groupA[%d] failed to join: %v", i, err)
+		}
+		if err := groupB[i].Join([]string{groupB[0].address}); err != nil {
+			t.Fatalf("groupB[%d] failed to join: %v", i, err)
+		}
+	}
+
+	waitForConvergence(t, groupA, time.Second)
+	waitForConvergence(t, groupB, time.Second)
+
+	// The partition heals: a single node from one half joins the other.
+	if err := groupA[0].Join([]string{groupB[0].address}); err != nil {
+		t.Fatalf("bridging join failed: %v", err)
+	}
+
+	waitForConvergence(t, all, 3*time.Second)
+}
+
+func TestJoin_FailsWhenNoPeerReachable(t *testing.T) {
+	node := newTestSWIM(t, 18299)
+	node.Start()
+	defer node.ShutDown()
+
+	err := node.Join([]string{fmt.Sprintf("127.0.0.1:%d", 18399)})
+	if err == nil {
+		t.Fatal("expected Join to fail when no peer address is reachable")
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"sync"
+	"time"
+)
+
+// nonceCache is a small bounded cache of recently-seen AES-GCM nonces,
+// used to reject replayed encrypted packets. A nonce is only ever valid
+// once for a given key; seeing it twice means a packet is either being
+// replayed or duplicated on the wire, so it is dropped either way.
+type nonceCache struct {
+	lock sync.Mutex
+
+	ttl     time.Duration
+	maxSize int
+
+	seen map[string]time.Time
+}
+
+func newNonceCache(ttl time.Duration, maxSize int) *nonceCache {
+	return &nonceCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// SeenBefore records nonce and reports whether it was already present
+// (and therefore should be treated as a replay).
+func (c *nonceCache) SeenBefore(nonce []byte) bool {
+	key := string(nonce)
+	now := time.Now()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.evictExpiredLocked(now)
+
+	if expiry, ok := c.seen[key]; ok && now.Before(expiry) {
+		return true
+	}
+
+	if len(c.seen) >= c.maxSize {
+		c.evictOldestLocked()
+	}
+
+	c.seen[key] = now.Add(c.ttl)
+	return false
+}
+
+func (c *nonceCache) evictExpiredLocked(now time.Time) {
+	for nonce, expiry := range c.seen {
+		if now.After(expiry) {
+			delete(c.seen, nonce)
+		}
+	}
+}
+
+// evictOldestLocked drops a single entry to make room. The cache favors
+// simplicity over strict LRU ordering since entries already expire via
+// ttl; this only guards against unbounded growth if ttl is set too high.
+func (c *nonceCache) evictOldestLocked() {
+	for nonce := range c.seen {
+		delete(c.seen, nonce)
+		return
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GetRTT estimates the round-trip time to addr from its Vivaldi
+// coordinate, without sending a probe. The estimate improves as more
+// direct pings are exchanged with addr (and, transitively, as its
+// coordinate is piggybacked by other members).
+func (s *SWIM) GetRTT(addr string) (time.Duration, error) {
+	member, ok := s.memberMap.Get(addr)
+	if !ok {
+		return 0, fmt.Errorf("swim: unknown member %s", addr)
+	}
+
+	local := s.memberMap.LocalMember()
+	return local.Coordinate.DistanceTo(member.Coordinate), nil
+}
+
+// SortByRTT returns a copy of addrs ordered from closest to farthest
+// estimated RTT from the local node. Addresses unknown to the memberMap
+// are treated as being at the origin and sort accordingly.
+func (s *SWIM) SortByRTT(addrs []string) []string {
+	sorted := make([]string, len(addrs))
+	copy(sorted, addrs)
+
+	local := s.memberMap.LocalMember()
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return local.Coordinate.DistanceTo(s.coordinateOf(sorted[i])) <
+			local.Coordinate.DistanceTo(s.coordinateOf(sorted[j]))
+	})
+
+	return sorted
+}
+
+func (s *SWIM) coordinateOf(addr string) Coordinate {
+	if member, ok := s.memberMap.Get(addr); ok {
+		return member.Coordinate
+	}
+	return newCoordinate()
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import "testing"
+
+func TestSWIM_GetRTTUnknownMember(t *testing.T) {
+	node := newTestSWIM(t, 18400)
+	defer node.ShutDown()
+
+	if _, err := node.GetRTT("127.0.0.1:19999"); err == nil {
+		t.Fatal("expected GetRTT to fail for an unknown member")
+	}
+}
+
+func TestSWIM_SortByRTTKeepsEveryUnknownMember(t *testing.T) {
+	node := newTestSWIM(t, 18401)
+	defer node.ShutDown()
+
+	addrs := []string{"a:1", "b:1", "c:1"}
+	sorted := node.SortByRTT(addrs)
+
+	if len(sorted) != len(addrs) {
+		t.Fatalf("expected %d addresses, got %d", len(addrs), len(sorted))
+	}
+	for _, addr := range addrs {
+		found := false
+		for _, s := range sorted {
+			if s == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("expected %s to be present in sorted result", addr)
+		}
+	}
+}
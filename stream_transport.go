@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+type StreamTransportConfig struct {
+	BindAddress string
+	BindPort    int
+}
+
+// StreamTransport is the TCP transport used for full state sync
+// (push/pull): a reliable, ordered channel alongside the best-effort
+// PacketTransport, listening on the same BindAddress:BindPort.
+type StreamTransport struct {
+	config *StreamTransportConfig
+
+	listener net.Listener
+
+	connCh chan net.Conn
+	quitCh chan struct{}
+}
+
+// NewStreamTransport listens on config.BindAddress:BindPort over TCP.
+func NewStreamTransport(config *StreamTransportConfig) (*StreamTransport, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.BindAddress, config.BindPort))
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &StreamTransport{
+		config:   config,
+		listener: listener,
+		connCh:   make(chan net.Conn),
+		quitCh:   make(chan struct{}),
+	}
+
+	go transport.listen()
+
+	return transport, nil
+}
+
+func (t *StreamTransport) listen() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			select {
+			case <-t.quitCh:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case t.connCh <- conn:
+		case <-t.quitCh:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// ConnCh returns the channel inbound connections are delivered on.
+func (t *StreamTransport) ConnCh() <-chan net.Conn {
+	return t.connCh
+}
+
+// DialTimeout opens an outbound connection to address.
+func (t *StreamTransport) DialTimeout(address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("tcp", address, timeout)
+}
+
+// Shutdown stops accepting new connections and closes the listener.
+func (t *StreamTransport) Shutdown() error {
+	close(t.quitCh)
+	return t.listener.Close()
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"sync"
+	"time"
+)
+
+// suspicionTimer tracks the per-member timer that runs while a member is
+// in the Suspect state. Its timeout starts at max and shrinks towards min
+// every time a different node independently confirms the same suspicion,
+// so a suspicion that many nodes agree on resolves quickly while a lone,
+// possibly mistaken, suspicion is given the full benefit of the doubt.
+type suspicionTimer struct {
+	lock sync.Mutex
+
+	// k is the number of independent confirmations needed to reach min.
+	k int
+
+	min time.Duration
+	max time.Duration
+
+	start time.Time
+	timer *time.Timer
+
+	// confirmations holds the addresses that have already independently
+	// confirmed this suspicion, so the same node cannot count twice.
+	confirmations map[string]struct{}
+}
+
+// newSuspicionTimer creates a suspicion timer seeded with the address
+// that triggered the suspicion (from) and fires timeoutFn if it is never
+// stopped or refuted first.
+func newSuspicionTimer(from string, k int, min, max time.Duration, timeoutFn func()) *suspicionTimer {
+	s := &suspicionTimer{
+		k:             k,
+		min:           min,
+		max:           max,
+		start:         time.Now(),
+		confirmations: map[string]struct{}{from: {}},
+	}
+
+	timeout := max
+	if k < 1 {
+		timeout = min
+	}
+
+	s.timer = time.AfterFunc(timeout, timeoutFn)
+	return s
+}
+
+// Confirm registers an independent confirmation of the suspicion from the
+// given address, shrinking the remaining timeout towards min. Returns
+// false if from already confirmed, or if k confirmations were already
+// reached.
+func (s *suspicionTimer) Confirm(from string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.confirmations)-1 >= s.k {
+		return false
+	}
+
+	if _, ok := s.confirmations[from]; ok {
+		return false
+	}
+	s.confirmations[from] = struct{}{}
+
+	n := len(s.confirmations) - 1
+	elapsed := time.Since(s.start)
+	remaining := s.timeoutFor(n) - elapsed
+
+	s.timer.Stop()
+	if remaining < 0 {
+		remaining = 0
+	}
+	s.timer.Reset(remaining)
+
+	return true
+}
+
+// Stop cancels the timer, e.g. because the member was refuted via a
+// higher-incarnation Alive.
+func (s *suspicionTimer) Stop() {
+	s.timer.Stop()
+}
+
+// timeoutFor linearly interpolates the timeout for n confirmations
+// between max (n == 0) and min (n >= k).
+func (s *suspicionTimer) timeoutFor(n int) time.Duration {
+	if s.k < 1 {
+		return s.min
+	}
+
+	frac := float64(n) / float64(s.k)
+	if frac > 1 {
+		frac = 1
+	}
+
+	return s.max - time.Duration(frac*float64(s.max-s.min))
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSuspicionTimer_FiresAtMaxWithoutConfirmations(t *testing.T) {
+	fired := make(chan struct{}, 1)
+
+	start := time.Now()
+	newSuspicionTimer("peerA", 3, 10*time.Millisecond, 60*time.Millisecond, func() {
+		fired <- struct{}{}
+	})
+
+	select {
+	case <-fired:
+		elapsed := time.Since(start)
+		if elapsed < 50*time.Millisecond {
+			t.Fatalf("suspicion fired too early without confirmations: %v", elapsed)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("suspicion never fired")
+	}
+}
+
+func TestSuspicionTimer_ShrinksTowardsMinWithConfirmations(t *testing.T) {
+	fired := make(chan struct{}, 1)
+
+	start := time.Now()
+	s := newSuspicionTimer("peerA", 3, 10*time.Millisecond, 200*time.Millisecond, func() {
+		fired <- struct{}{}
+	})
+
+	// Three independent peers confirm the same suspicion; the remaining
+	// timeout should shrink all the way down near min instead of
+	// running out the full max.
+	for i := 0; i < 3; i++ {
+		if !s.Confirm(fmt.Sprintf("peer%d", i)) {
+			t.Fatalf("expected confirmation %d to be accepted", i)
+		}
+	}
+
+	select {
+	case <-fired:
+		elapsed := time.Since(start)
+		if elapsed > 100*time.Millisecond {
+			t.Fatalf("expected suspicion to fire close to min after k confirmations, took %v", elapsed)
+		}
+	case <-time.After(300 * time.Millisecond):
+		t.Fatal("suspicion never fired after shrinking")
+	}
+}
+
+func TestSuspicionTimer_DuplicateConfirmationIgnored(t *testing.T) {
+	s := newSuspicionTimer("peerA", 3, 10*time.Millisecond, 200*time.Millisecond, func() {})
+	defer s.Stop()
+
+	if !s.Confirm("peerB") {
+		t.Fatal("expected first confirmation from peerB to be accepted")
+	}
+	if s.Confirm("peerB") {
+		t.Fatal("expected duplicate confirmation from peerB to be rejected")
+	}
+}
+
+func TestSuspicionTimer_StopPreventsFiring(t *testing.T) {
+	fired := make(chan struct{}, 1)
+
+	s := newSuspicionTimer("peerA", 3, 10*time.Millisecond, 30*time.Millisecond, func() {
+		fired <- struct{}{}
+	})
+	s.Stop()
+
+	select {
+	case <-fired:
+		t.Fatal("refuted suspicion should not have fired")
+	case <-time.After(80 * time.Millisecond):
+		// expected: no false positive after refutation.
+	}
+}
@@ -17,6 +17,10 @@
 package swim
 
 import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"log"
@@ -25,10 +29,36 @@ import (
 	"github.com/it-chain/iLogger"
 )
 
+const (
+	// maxPiggybackEntries caps how many piggyback updates ride on a
+	// single ping/ack/indirect-ping, regardless of the byte budget below.
+	maxPiggybackEntries = 6
+
+	// udpMTU is a conservative safe UDP payload size, chosen to avoid IP
+	// fragmentation on typical networks.
+	udpMTU = 1400
+
+	// baseMessageOverhead is a rough estimate of the marshaled size of a
+	// ping/ack/indirect-ping envelope without its piggyback entries, so
+	// attaching them never pushes the packet past udpMTU.
+	baseMessageOverhead = 96
+
+	maxPiggybackBytes = udpMTU - baseMessageOverhead
+
+	// maxAddrBookNew and maxAddrBookTried cap the address book's two
+	// buckets, bounding memory regardless of how many addresses PEX
+	// hands out over the node's lifetime.
+	maxAddrBookNew   = 256
+	maxAddrBookTried = 256
+)
+
 type Config struct {
 
-	// The maximum number of times the same piggyback data can be queried
-	MaxlocalCount int
+	// RetransmitMult scales how many times a single piggyback update may
+	// be retransmitted before the broadcast queue drops it:
+	// RetransmitMult * ceil(log(N+1)), where N is the current cluster
+	// size. See BroadcastQueue.
+	RetransmitMult int
 
 	// T is the the period of the probe
 	T int
@@ -39,6 +69,36 @@ type Config struct {
 	// K is the number of members to send indirect ping
 	K int
 
+	// SuspicionMult scales the initial suspicion timeout:
+	// SuspicionMult * log(N+1) * T. As independent peers confirm the same
+	// suspicion, that timeout shrinks down to SuspicionMult * log(N+1) *
+	// T / K.
+	SuspicionMult int
+
+	// PushPullInterval is how often, in milliseconds, this node
+	// initiates a full state sync (see pushpull.go) with a random
+	// member. 0 disables periodic push/pull - Join still performs one.
+	PushPullInterval int
+
+	// PushPullTimeout is the TCP dial timeout, in milliseconds, for a
+	// push/pull full state sync (see pushpull.go). Unlike AckTimeOut -
+	// which only needs to cover a single UDP round trip - this has to
+	// cover a full TCP handshake plus a two-way state exchange, so it is
+	// configured separately. <= 0 falls back to defaultPushPullTimeout.
+	PushPullTimeout int
+
+	// PEXInterval is how often, in milliseconds, this node requests a
+	// peer sample from a random member and validates a sample of
+	// not-yet-confirmed addresses from its address book (see
+	// addr_book.go and pex.go). 0 disables periodic PEX.
+	PEXInterval int
+
+	// PEXSampleSize is how many addresses this node asks for per PEX
+	// exchange, and how many addresses Join samples from the address
+	// book when no peerAddresses are supplied. A PEX answer holds as
+	// many addresses as the requester asked for.
+	PEXSampleSize int
+
 	// my address and port
 	BindAddress string
 	BindPort    int
@@ -49,18 +109,39 @@ type SWIM struct {
 	// Swim Config
 	config *Config
 
+	// address ("host:port") of this node, also its key in memberMap.
+	address string
+
 	// Currently connected memberList
 	memberMap *MemberMap
 
 	messageEndpoint *MessageEndpoint
 
-	priorityPBStore *PriorityPBStore
+	streamTransport *StreamTransport
+
+	// awareness is this node's self-reported health score, used to scale
+	// probe/ack timeouts under load. See the Lifeguard paper.
+	awareness *Awareness
 
 	// FailureDetector quit channel
 	quitFD chan struct{}
 
 	// Piggyback-store which store messages about recent state changes of member.
 	pbkStore PBkStore
+
+	// addrBook persists every address this node has heard about via PEX
+	// or Join, so the cluster can be re-bootstrapped after its original
+	// seeds are gone.
+	addrBook *AddrBook
+
+	// seq generates unique message ids for outstanding pings.
+	seq uint64
+
+	ackLock    sync.Mutex
+	ackWaiters map[string]chan pb.Message
+
+	suspicionLock sync.Mutex
+	suspicions    map[string]*suspicionTimer
 }
 
 func New(config *Config, messageEndpointConfig MessageEndpointConfig, awareness *Awareness) *SWIM {
@@ -68,14 +149,31 @@ func New(config *Config, messageEndpointConfig MessageEndpointConfig, awareness
 		panic("T time must be longer than ack time-out")
 	}
 
+	address := fmt.Sprintf("%s:%d", config.BindAddress, config.BindPort)
+
+	streamTransport, err := NewStreamTransport(&StreamTransportConfig{
+		BindAddress: config.BindAddress,
+		BindPort:    config.BindPort,
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
 	swim := SWIM{
 		config:          config,
-		memberMap:       NewMemberMap(),
+		address:         address,
+		memberMap:       NewMemberMap(address),
 		messageEndpoint: nil,
-		priorityPBStore: NewPriorityPBStore(config.MaxlocalCount),
+		streamTransport: streamTransport,
+		awareness:       awareness,
 		quitFD:          make(chan struct{}),
+		ackWaiters:      make(map[string]chan pb.Message),
+		suspicions:      make(map[string]*suspicionTimer),
 	}
 
+	swim.pbkStore = NewBroadcastQueue(config.RetransmitMult, swim.memberMap.Len)
+	swim.addrBook = NewAddrBook(address, maxAddrBookNew, maxAddrBookTried)
+
 	messageEndpoint := messageEndpointFactory(config, messageEndpointConfig, &swim, awareness)
 	swim.messageEndpoint = messageEndpoint
 
@@ -84,8 +182,10 @@ func New(config *Config, messageEndpointConfig MessageEndpointConfig, awareness
 
 func messageEndpointFactory(config *Config, messageEndpointConfig MessageEndpointConfig, messageHandler MessageHandler, awareness *Awareness) *MessageEndpoint {
 	packetTransportConfig := PacketTransportConfig{
-		BindAddress: config.BindAddress,
-		BindPort:    config.BindPort,
+		BindAddress:       config.BindAddress,
+		BindPort:          config.BindPort,
+		Keyring:           messageEndpointConfig.Keyring,
+		RequireEncryption: messageEndpointConfig.RequireEncryption,
 	}
 
 	packetTransport, err := NewPacketTransport(&packetTransportConfig)
@@ -101,24 +201,29 @@ func messageEndpointFactory(config *Config, messageEndpointConfig MessageEndpoin
 	return messageEndpoint
 }
 
-// Start SWIM protocol.
+// Start SWIM protocol: the probe-based failure detector, the inbound
+// push/pull listener and (if configured) periodic push/pull and
+// periodic PEX.
 func (s *SWIM) Start() {
-
+	go s.startFailureDetector()
+	go s.listenPushPull()
+	go s.periodicPushPull()
+	go s.periodicPEX()
 }
 
-// Dial to the all peerAddresses and exchange memberList.
-func (s *SWIM) Join(peerAddresses []string) error {
-	return nil
-}
+// Join is implemented in pushpull.go.
 
 // Gossip message to p2p network.
 func (s *SWIM) Gossip(msg []byte) {
 
 }
 
-// Shutdown the running swim.
+// Shutdown the running swim. Closing quitFD (rather than sending on it)
+// lets every goroutine spawned by Start observe the shutdown.
 func (s *SWIM) ShutDown() {
-	s.quitFD <- struct{}{}
+	close(s.quitFD)
+	s.messageEndpoint.Shutdown()
+	s.streamTransport.Shutdown()
 }
 
 // Total Failure Detection is performed for each` T`. (ref: https://github.com/DE-labtory/swim/edit/develop/docs/Docs.md)
@@ -143,61 +248,271 @@ func (s *SWIM) ShutDown() {
 // 2. Probe the member.
 // 3. After finishing probing all members, Reset memberMap
 func (s *SWIM) startFailureDetector() {
+	for {
+		select {
+		case <-s.quitFD:
+			return
+		default:
+		}
 
-	go func() {
-		for {
-			// Get copy of current members from memberMap.
-			members := s.memberMap.GetMembers()
-			for _, member := range members {
-				s.probe(member)
+		roundStart := time.Now()
+
+		// Get copy of current members from memberMap.
+		members := s.memberMap.GetMembers()
+		for _, member := range members {
+			select {
+			case <-s.quitFD:
+				return
+			default:
 			}
+			s.probe(member)
+		}
 
-			// Reset memberMap.
-			s.memberMap.Reset()
+		// probe returns immediately for a local or already-Dead member
+		// (and Dead members are never pruned from memberMap), so a round
+		// with no one left to actually ping would otherwise spin with no
+		// pacing at all. Make sure every round takes at least T.
+		T := s.awareness.ScaleTimeout(time.Duration(s.config.T) * time.Millisecond)
+		if remaining := T - time.Since(roundStart); remaining > 0 {
+			select {
+			case <-time.After(remaining):
+			case <-s.quitFD:
+				return
+			}
 		}
-	}()
 
-	<-s.quitFD
+		// Reset memberMap.
+		s.memberMap.Reset()
+	}
 }
 
 // probe function
 //
-// 1. Send ping to the member(j) during the ack-timeout (time less than T).
-//    Return if ack message arrives on ack-timeout.
+// 1. Send ping to the member(j) during the ack-timeout scaled by this
+//    node's current health score (time less than T, itself scaled the
+//    same way). Return if ack message arrives on ack-timeout.
 //
 // 2. selects k number of members from the memberMap and sends indirect-ping(request k members to ping the member(j)).
 //    The nodes (that receive the indirect-ping) ping to the member(j) and ack when they receive ack from the member(j).
 //
 // 3. At the end of T, SWIM checks to see if ack was received from k members, and if there is no message,
-//    The member(j) is judged to be failed, so check the member(j) as suspected or delete the member(j) from memberMap.
+//    The member(j) is judged to be failed, so it is marked Suspect and a suspicion timer is started - it is
+//    no longer deleted outright. See suspectMember / suspicion.go for the Lifeguard-style timeout.
 //
-
 func (s *SWIM) probe(member Member) {
 
-	if member.Status == Dead {
+	if member.Status == Dead || s.memberMap.IsLocal(member.Address) {
 		return
 	}
 
-	end := make(chan struct{}, 1)
-	defer close(end)
+	T := s.awareness.ScaleTimeout(time.Duration(s.config.T) * time.Millisecond)
+	ackTimeOut := s.awareness.ScaleTimeout(time.Duration(s.config.AckTimeOut) * time.Millisecond)
 
-	go func() {
+	msgID := s.nextMessageID()
+	ackCh := s.registerAckWaiter(msgID)
+	defer s.deregisterAckWaiter(msgID)
 
-		// Ping to member
-		time.Sleep(1 * time.Second)
-		end <- struct{}{}
-	}()
+	sendTime := time.Now()
+	s.sendPing(msgID, member.Address)
 
-	T := time.NewTimer(time.Millisecond * time.Duration(s.config.T))
+	ackTimer := time.NewTimer(ackTimeOut)
+	select {
+	case ack := <-ackCh:
+		ackTimer.Stop()
+		// Direct ack received in time: this node's view of its own
+		// health improves.
+		s.awareness.ApplyDelta(-1)
+		s.updateLocalCoordinate(ack, time.Since(sendTime))
+		return
+	case <-ackTimer.C:
+		// Missed direct ack.
+		s.awareness.ApplyDelta(1)
+	}
 
+	// Fall back to indirect ping via k relays.
+	s.awareness.ApplyDelta(1)
+
+	relays := s.memberMap.RandomMembers(s.config.K, member.Address)
+	for _, relay := range relays {
+		s.sendIndirectPing(msgID, relay.Address, member.Address)
+	}
+
+	remaining := T - ackTimeOut
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	roundTimer := time.NewTimer(remaining)
 	select {
-	case <-end:
-		// Ended
+	case <-ackCh:
+		roundTimer.Stop()
+		s.awareness.ApplyDelta(-1)
 		return
-	case <-T.C:
-		// Suspect the member.
+	case <-roundTimer.C:
+		s.suspectMember(member.Address, member.Incarnation)
+	}
+}
+
+// nextMessageID returns a process-unique id to correlate an outstanding
+// ping (direct or indirect) with its eventual ack.
+func (s *SWIM) nextMessageID() string {
+	return fmt.Sprintf("%s-%d", s.address, atomic.AddUint64(&s.seq, 1))
+}
+
+func (s *SWIM) registerAckWaiter(msgID string) chan pb.Message {
+	ch := make(chan pb.Message, 1)
+
+	s.ackLock.Lock()
+	s.ackWaiters[msgID] = ch
+	s.ackLock.Unlock()
+
+	return ch
+}
+
+func (s *SWIM) deregisterAckWaiter(msgID string) {
+	s.ackLock.Lock()
+	delete(s.ackWaiters, msgID)
+	s.ackLock.Unlock()
+}
+
+// nextPiggyBacks draws the next batch of piggyback entries to attach to
+// an outgoing message, bounded so they never push the message past
+// maxPiggybackBytes.
+func (s *SWIM) nextPiggyBacks() []*pb.PiggyBack {
+	entries := s.pbkStore.Get(maxPiggybackEntries, maxPiggybackBytes)
+
+	piggyBacks := make([]*pb.PiggyBack, len(entries))
+	for i := range entries {
+		piggyBacks[i] = &entries[i]
+	}
+	return piggyBacks
+}
+
+func (s *SWIM) sendPing(msgID string, target string) {
+	s.messageEndpoint.Send(target, pb.Message{
+		Id:      msgID,
+		Address: s.address,
+		Payload: &pb.Message_Ping{
+			Ping: &pb.Ping{},
+		},
+		PiggyBacks: s.nextPiggyBacks(),
+		Coordinate: toPbCoordinate(s.memberMap.LocalMember().Coordinate),
+	})
+}
+
+func (s *SWIM) sendIndirectPing(msgID string, relay string, target string) {
+	s.messageEndpoint.Send(relay, pb.Message{
+		Id:      msgID,
+		Address: s.address,
+		Payload: &pb.Message_IndirectPing{
+			IndirectPing: &pb.IndirectPing{Target: target},
+		},
+		PiggyBacks: s.nextPiggyBacks(),
+		Coordinate: toPbCoordinate(s.memberMap.LocalMember().Coordinate),
+	})
+}
+
+// updateLocalCoordinate applies one Vivaldi update step to the local
+// node's coordinate using the RTT just measured against a direct ack,
+// and the acking peer's piggybacked coordinate.
+func (s *SWIM) updateLocalCoordinate(ack pb.Message, rtt time.Duration) {
+	other, ok := fromPbCoordinate(ack.Coordinate)
+	if !ok {
 		return
 	}
+
+	local := s.memberMap.LocalMember().Coordinate
+	s.memberMap.UpdateCoordinate(s.address, local.update(other, rtt))
+}
+
+// suspectMember transitions address to Suspect and starts its Lifeguard
+// suspicion timer: an initial timeout of SuspicionMult * log(N+1) * T
+// that shrinks towards SuspicionMult * log(N+1) * T / K as independent
+// peers corroborate the suspicion (see handlePbk), and that is cancelled
+// outright by a higher-incarnation Alive refutation.
+func (s *SWIM) suspectMember(address string, incarnation uint32) {
+	if !s.memberMap.Suspect(address, incarnation) {
+		return
+	}
+
+	s.pbkStore.Push(pb.PiggyBack{
+		Type:        pb.PiggyBack_Suspect,
+		Id:          address,
+		Address:     address,
+		Incarnation: incarnation,
+	})
+
+	timeout := s.suspicionTimeout()
+
+	s.suspicionLock.Lock()
+	defer s.suspicionLock.Unlock()
+
+	if existing, ok := s.suspicions[address]; ok {
+		existing.Stop()
+	}
+
+	k := s.config.K
+	min := timeout / time.Duration(max(k, 1))
+
+	s.suspicions[address] = newSuspicionTimer(s.address, k, min, timeout, func() {
+		s.confirmMember(address, incarnation)
+	})
+}
+
+// confirmMember transitions address to Dead once its suspicion timer
+// fires without having been refuted.
+func (s *SWIM) confirmMember(address string, incarnation uint32) {
+	s.suspicionLock.Lock()
+	delete(s.suspicions, address)
+	s.suspicionLock.Unlock()
+
+	if !s.memberMap.Confirm(address, incarnation) {
+		return
+	}
+
+	s.pbkStore.Push(pb.PiggyBack{
+		Type:        pb.PiggyBack_Confirm,
+		Id:          address,
+		Address:     address,
+		Incarnation: incarnation,
+	})
+}
+
+// suspicionTimeout computes SuspicionMult * log(N+1) * T.
+func (s *SWIM) suspicionTimeout() time.Duration {
+	n := float64(s.memberMap.Len())
+	T := time.Duration(s.config.T) * time.Millisecond
+
+	scale := float64(s.config.SuspicionMult) * math.Log(n+1)
+	if scale < 1 {
+		scale = 1
+	}
+
+	return time.Duration(scale * float64(T))
+}
+
+// refuteSelf bumps the local node's incarnation and broadcasts a fresh
+// Alive, in response to a Suspect piggyback naming this node.
+func (s *SWIM) refuteSelf() {
+	local := s.memberMap.LocalMember()
+	newIncarnation := local.Incarnation + 1
+
+	s.memberMap.Alive(s.address, newIncarnation)
+	s.awareness.ApplyDelta(1)
+
+	s.pbkStore.Push(pb.PiggyBack{
+		Type:        pb.PiggyBack_Alive,
+		Id:          s.address,
+		Address:     s.address,
+		Incarnation: newIncarnation,
+	})
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
 
 // handler interface to handle received message
@@ -216,35 +531,89 @@ type MessageHandler interface {
 //
 func (s *SWIM) handle(msg pb.Message) {
 
-	s.handlePbk(msg.PiggyBack)
+	for _, piggyBack := range msg.PiggyBacks {
+		s.handlePbk(msg.Address, piggyBack)
+	}
+
+	if !s.memberMap.IsLocal(msg.Address) {
+		if coord, ok := fromPbCoordinate(msg.Coordinate); ok {
+			s.memberMap.UpdateCoordinate(msg.Address, coord)
+		}
+	}
 
 	switch msg.Payload.(type) {
 	case *pb.Message_Ping:
 		s.pingHandler(msg)
 	case *pb.Message_Ack:
-		// handle ack
+		s.ackHandler(msg)
 	case *pb.Message_IndirectPing:
-		// handle indirect ping
+		// indirectPingHandler blocks waiting for the target's ack, which
+		// arrives on this same receive loop - run it on its own
+		// goroutine so the loop keeps draining incoming packets (that
+		// ack included) instead of deadlocking itself.
+		go s.indirectPingHandler(msg)
+	case *pb.Message_PEXRequest:
+		s.pexRequestHandler(msg)
+	case *pb.Message_PEXResponse:
+		s.pexResponseHandler(msg)
 	default:
 
 	}
 }
 
 // handle piggyback related to member status
-func (s *SWIM) handlePbk(piggyBack *pb.PiggyBack) {
+func (s *SWIM) handlePbk(from string, piggyBack *pb.PiggyBack) {
+
+	if piggyBack == nil {
+		return
+	}
 
 	// Check if piggyback message changes memberMap.
 	hasChanged := false
 
 	switch piggyBack.Type {
 	case pb.PiggyBack_Alive:
-		// Call Alive function in memberMap.
+		hasChanged = s.memberMap.Alive(piggyBack.Address, piggyBack.Incarnation)
+		if hasChanged {
+			s.cancelSuspicion(piggyBack.Address)
+		}
+
 	case pb.PiggyBack_Confirm:
-		// Call Confirm function in memberMap.
+		if s.memberMap.IsLocal(piggyBack.Address) {
+			// Someone (wrongly) believes we are dead: refute the same
+			// way we refute a Suspect about ourselves.
+			s.refuteSelf()
+			return
+		}
+
+		hasChanged = s.memberMap.Confirm(piggyBack.Address, piggyBack.Incarnation)
+		if hasChanged {
+			s.cancelSuspicion(piggyBack.Address)
+		}
+
 	case pb.PiggyBack_Suspect:
-		// Call Suspect function in memberMap.
+		if s.memberMap.IsLocal(piggyBack.Address) {
+			// Someone suspects us: refute by bumping our incarnation
+			// and re-broadcasting Alive, rather than accepting the
+			// Suspect transition.
+			s.refuteSelf()
+			return
+		}
+
+		if s.confirmSuspicion(piggyBack.Address, from) {
+			// A different node independently suspects the same
+			// member: shrink its remaining suspicion timeout instead
+			// of treating this as a brand-new state change.
+			return
+		}
+
+		hasChanged = s.memberMap.Suspect(piggyBack.Address, piggyBack.Incarnation)
+		if hasChanged {
+			s.startSuspicionFromPbk(piggyBack.Address, piggyBack.Incarnation, from)
+		}
+
 	default:
-		// PiggyBack_type error
+		iLogger.Error(nil, "unknown piggyback type")
 	}
 
 	// Push piggyback when status of membermap has updated.
@@ -255,31 +624,111 @@ func (s *SWIM) handlePbk(piggyBack *pb.PiggyBack) {
 	}
 }
 
-// handlePing send back Ack message by response
-func (s *SWIM) pingHandler(msg pb.Message) {
-	Address := s.config.BindAddress + ":" + string(s.config.BindPort)
+// startSuspicionFromPbk starts a suspicion timer for a member we did not
+// suspect ourselves, but learned about via gossip.
+func (s *SWIM) startSuspicionFromPbk(address string, incarnation uint32, from string) {
+	timeout := s.suspicionTimeout()
+	k := s.config.K
+	min := timeout / time.Duration(max(k, 1))
 
-	piggyBack, err := s.priorityPBStore.Get()
-	if err != nil {
-		iLogger.Error(nil, err.Error())
+	s.suspicionLock.Lock()
+	defer s.suspicionLock.Unlock()
+
+	if _, ok := s.suspicions[address]; ok {
+		return
 	}
 
+	s.suspicions[address] = newSuspicionTimer(from, k, min, timeout, func() {
+		s.confirmMember(address, incarnation)
+	})
+}
+
+// confirmSuspicion feeds an independent Suspect report into the running
+// suspicion timer for address, if one exists.
+func (s *SWIM) confirmSuspicion(address string, from string) bool {
+	s.suspicionLock.Lock()
+	timer, ok := s.suspicions[address]
+	s.suspicionLock.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	return timer.Confirm(from)
+}
+
+func (s *SWIM) cancelSuspicion(address string) {
+	s.suspicionLock.Lock()
+	defer s.suspicionLock.Unlock()
+
+	if timer, ok := s.suspicions[address]; ok {
+		timer.Stop()
+		delete(s.suspicions, address)
+	}
+}
+
+// handlePing send back Ack message by response
+func (s *SWIM) pingHandler(msg pb.Message) {
 	s.messageEndpoint.Send(msg.Address, pb.Message{
 		Id:      msg.Id,
-		Address: Address,
+		Address: s.address,
 		Payload: &pb.Message_Ack{
 			Ack: &pb.Ack{Payload: ""},
 		},
-		PiggyBack: &piggyBack,
+		PiggyBacks: s.nextPiggyBacks(),
+		Coordinate: toPbCoordinate(s.memberMap.LocalMember().Coordinate),
 	})
 }
 
-//TODO
+// ackHandler routes an incoming ack to whichever probe (direct or
+// indirect) is waiting on it, keyed by message id. The full message is
+// forwarded (not just its Ack payload) so the waiting probe can read the
+// piggybacked Coordinate too.
 func (s *SWIM) ackHandler(msg pb.Message) {
+	s.ackLock.Lock()
+	ch, ok := s.ackWaiters[msg.Id]
+	s.ackLock.Unlock()
 
+	if !ok {
+		return
+	}
+
+	select {
+	case ch <- msg:
+	default:
+	}
 }
 
-//TODO
+// indirectPingHandler relays a ping to IndirectPing.Target on behalf of
+// msg.Address, and forwards the target's ack back to it.
 func (s *SWIM) indirectPingHandler(msg pb.Message) {
+	indirectPing := msg.GetIndirectPing()
+	if indirectPing == nil {
+		return
+	}
+
+	ackCh := s.registerAckWaiter(msg.Id)
+	defer s.deregisterAckWaiter(msg.Id)
 
+	s.sendPing(msg.Id, indirectPing.Target)
+
+	ackTimeOut := s.awareness.ScaleTimeout(time.Duration(s.config.AckTimeOut) * time.Millisecond)
+	timer := time.NewTimer(ackTimeOut)
+	defer timer.Stop()
+
+	select {
+	case <-ackCh:
+		s.messageEndpoint.Send(msg.Address, pb.Message{
+			Id:      msg.Id,
+			Address: s.address,
+			Payload: &pb.Message_Ack{
+				Ack: &pb.Ack{Payload: ""},
+			},
+			PiggyBacks: s.nextPiggyBacks(),
+			Coordinate: toPbCoordinate(s.memberMap.LocalMember().Coordinate),
+		})
+	case <-timer.C:
+		// Target never acked the relayed ping: the original prober
+		// will time out on its own and move to suspicion.
+	}
 }
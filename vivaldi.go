@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/DE-labtory/swim/pb"
+)
+
+// vivaldiDimensions is the number of dimensions of the Euclidean part of
+// the coordinate space; height (see Coordinate.Height) models access
+// links that don't obey the triangle inequality and sits outside it.
+const vivaldiDimensions = 8
+
+const (
+	vivaldiCe         = 0.25
+	vivaldiCd         = 0.25
+	vivaldiHeightMin  = 1.0e-4
+	vivaldiErrorMin   = 1.0e-5
+	vivaldiErrorStart = 1.5
+)
+
+// Coordinate is a node's position in the Vivaldi network coordinate
+// space: Vec is the Euclidean part, Height models the "last mile" link
+// that Euclidean space can't represent, and Error tracks how much this
+// node still trusts its own position.
+type Coordinate struct {
+	Vec    []float64
+	Height float64
+	Error  float64
+}
+
+// newCoordinate returns the coordinate a node starts at before any RTT
+// measurements refine it: the origin, with maximum uncertainty.
+func newCoordinate() Coordinate {
+	return Coordinate{
+		Vec:    make([]float64, vivaldiDimensions),
+		Height: vivaldiHeightMin,
+		Error:  vivaldiErrorStart,
+	}
+}
+
+// DistanceTo estimates the network latency between this coordinate and
+// other.
+func (c Coordinate) DistanceTo(other Coordinate) time.Duration {
+	dist := euclideanDistance(c.Vec, other.Vec) + c.Height + other.Height
+	if dist < 0 {
+		dist = 0
+	}
+	return time.Duration(dist * float64(time.Second))
+}
+
+// update applies one step of the Vivaldi algorithm: having just measured
+// rtt to a peer whose advertised coordinate is other, it returns this
+// node's adjusted coordinate.
+func (c Coordinate) update(other Coordinate, rtt time.Duration) Coordinate {
+	rttSeconds := rtt.Seconds()
+	if rttSeconds <= 0 {
+		rttSeconds = 1.0e-6
+	}
+
+	est := euclideanDistance(c.Vec, other.Vec) + c.Height + other.Height
+
+	errSample := math.Abs(rttSeconds-est) / rttSeconds
+
+	w := c.Error / (c.Error + other.Error)
+	if math.IsNaN(w) {
+		w = 0.5
+	}
+
+	newError := errSample*vivaldiCe*w + c.Error*(1-vivaldiCe*w)
+	newError = clampFloat(newError, vivaldiErrorMin, 1.0)
+
+	delta := vivaldiCd * w
+	direction, ok := unitVector(vectorSub(c.Vec, other.Vec))
+	if !ok {
+		direction = randomUnitVector(len(c.Vec))
+	}
+
+	scale := delta * (rttSeconds - est)
+
+	newVec := make([]float64, len(c.Vec))
+	for i := range c.Vec {
+		newVec[i] = c.Vec[i] + direction[i]*scale
+	}
+
+	newHeight := c.Height + scale
+	if newHeight < vivaldiHeightMin {
+		newHeight = vivaldiHeightMin
+	}
+
+	return Coordinate{Vec: newVec, Height: newHeight, Error: newError}
+}
+
+func euclideanDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func vectorSub(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		out[i] = a[i] - b[i]
+	}
+	return out
+}
+
+// unitVector normalizes v, reporting false if v is (close enough to) the
+// zero vector that the direction is undefined.
+func unitVector(v []float64) ([]float64, bool) {
+	mag := euclideanDistance(v, make([]float64, len(v)))
+	if mag < vivaldiErrorMin {
+		return nil, false
+	}
+
+	out := make([]float64, len(v))
+	for i := range v {
+		out[i] = v[i] / mag
+	}
+	return out, true
+}
+
+// randomUnitVector breaks ties when two coordinates coincide, so the
+// update step always has a direction to move in.
+func randomUnitVector(n int) []float64 {
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = rand.Float64() - 0.5
+	}
+
+	if unit, ok := unitVector(v); ok {
+		return unit
+	}
+
+	// Vanishingly unlikely, but fall back to a fixed axis rather than
+	// recursing forever.
+	v[0] = 1
+	return v
+}
+
+func clampFloat(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func toPbCoordinate(c Coordinate) *pb.Coordinate {
+	return &pb.Coordinate{
+		Vec:    c.Vec,
+		Height: c.Height,
+		Error:  c.Error,
+	}
+}
+
+func fromPbCoordinate(c *pb.Coordinate) (Coordinate, bool) {
+	if c == nil {
+		return Coordinate{}, false
+	}
+	return Coordinate{Vec: c.Vec, Height: c.Height, Error: c.Error}, true
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 De-labtory
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package swim
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCoordinate_UpdateConvergesTowardsMeasuredRTT(t *testing.T) {
+	a := newCoordinate()
+	b := newCoordinate()
+	b.Vec[0] = 0.2
+
+	target := 100 * time.Millisecond
+
+	initialErr := math.Abs(a.DistanceTo(b).Seconds() - target.Seconds())
+
+	for i := 0; i < 200; i++ {
+		a = a.update(b, target)
+	}
+
+	finalErr := math.Abs(a.DistanceTo(b).Seconds() - target.Seconds())
+	if finalErr >= initialErr {
+		t.Fatalf("expected estimated distance to converge towards %v, started off by %v, ended off by %v", target, initialErr, finalErr)
+	}
+
+	if finalErr > target.Seconds()*0.1 {
+		t.Fatalf("expected estimate within 10%% of %v after convergence, got off by %v", target, finalErr)
+	}
+}
+
+func TestCoordinate_DistanceToIsNeverNegative(t *testing.T) {
+	a := newCoordinate()
+	b := newCoordinate()
+	b.Height = -1
+
+	if a.DistanceTo(b) < 0 {
+		t.Fatal("expected DistanceTo to never return a negative duration")
+	}
+}
+
+// TestCoordinate_ConvergesOnSyntheticLatencyMatrix simulates a small ring
+// of nodes with known pairwise RTTs and repeatedly applies Vivaldi
+// updates over every ordered pair in turn, the same way probe/ack exchanges would
+// in a real cluster. After enough rounds every node's coordinate should
+// estimate every other node's RTT within a small error bound.
+func TestCoordinate_ConvergesOnSyntheticLatencyMatrix(t *testing.T) {
+	const n = 5
+	latency := [n][n]time.Duration{}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			d := i - j
+			if d < 0 {
+				d = -d
+			}
+			latency[i][j] = time.Duration(10*d) * time.Millisecond
+		}
+	}
+
+	coords := make([]Coordinate, n)
+	for i := range coords {
+		coords[i] = newCoordinate()
+	}
+
+	for round := 0; round < 2000; round++ {
+		i := round % n
+		j := (round / n) % n
+		if i == j {
+			continue
+		}
+		coords[i] = coords[i].update(coords[j], latency[i][j])
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			estimate := coords[i].DistanceTo(coords[j])
+			actual := latency[i][j]
+
+			errBound := 10 * time.Millisecond
+			diff := estimate - actual
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > errBound {
+				t.Fatalf("node %d estimate of node %d RTT off by %v (estimate %v, actual %v)", i, j, diff, estimate, actual)
+			}
+		}
+	}
+}